@@ -3,6 +3,8 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"song-library/domain"
+	"song-library/problem"
 	"song-library/service"
 )
 
@@ -22,8 +24,8 @@ func NewInfoController(service *service.SongService) *InfoController {
 //	@Param			group	query	string	true	"Название группы"
 //	@Param			song	query	string	true	"Название песни"
 //	@Success		200		{object}	domain.SongDetail
-//	@Failure		400		{string}	string	"Параметры обязательны"
-//	@Failure		500		{string}	string	"Ошибка получения данных"
+//	@Failure		400		{object}	problem.Problem	"Параметры обязательны"
+//	@Failure		500		{object}	problem.Problem	"Ошибка получения данных"
 //	@Router			/info [get]
 func (c *InfoController) InfoHandler(w http.ResponseWriter, r *http.Request) {
 	group := r.PathValue("group")
@@ -31,7 +33,14 @@ func (c *InfoController) InfoHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Проверка обязательных параметров
 	if group == "" || song == "" {
-		http.Error(w, "Параметры 'group' и 'song' обязательны", http.StatusBadRequest)
+		err := &domain.ValidationError{}
+		if group == "" {
+			err.Add("group", "required")
+		}
+		if song == "" {
+			err.Add("song", "required")
+		}
+		problem.Write(w, r, "op.invalid_request", err)
 		return
 	}
 
@@ -53,3 +62,15 @@ You set my soul alight`,
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// CacheStatsHandler возвращает счетчики кэша деталей песен.
+//
+//	@Summary		Статистика кэша деталей песен
+//	@Description	Получение счетчиков попаданий и промахов кэша ответов внешнего API.
+//	@Tags			Info
+//	@Success		200	{object}	service.CacheStats
+//	@Router			/info/cache [get]
+func (c *InfoController) CacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.service.CacheStats())
+}
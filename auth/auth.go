@@ -0,0 +1,46 @@
+// Package auth передает через контекст запроса пользователя, от имени
+// которого выполняются изменения. Сервис не проверяет учетные данные сам:
+// пользователя определяет прокси или шлюз перед ним и передает его имя в
+// заголовке X-Authenticated-User.
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Header — заголовок с именем пользователя, проверенного шлюзом.
+const Header = "X-Authenticated-User"
+
+// maxUserLength ограничивает длину имени пользователя в символах.
+const maxUserLength = 255
+
+type contextKey struct{}
+
+// WithUser возвращает контекст с именем пользователя.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// User возвращает имя пользователя из контекста или пустую строку, если
+// пользователь неизвестен.
+func User(ctx context.Context) string {
+	user, _ := ctx.Value(contextKey{}).(string)
+	return user
+}
+
+// Middleware передает обработчику через контекст имя пользователя из
+// заголовка X-Authenticated-User.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := strings.TrimSpace(r.Header.Get(Header))
+		if runes := []rune(user); len(runes) > maxUserLength {
+			user = string(runes[:maxUserLength])
+		}
+		if user != "" {
+			r = r.WithContext(WithUser(r.Context(), user))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,387 @@
+// Package backup создает и восстанавливает полные архивы библиотеки песен.
+//
+// Архив представляет собой tar.gz, первым файлом которого идет manifest.json
+// с версией схемы базы данных, а за ним по одному NDJSON-файлу на таблицу.
+// Строки таблиц сохраняются через row_to_json, поэтому архив переносит все
+// колонки без потерь, включая служебные.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const (
+	// FormatName обозначает формат архива в манифесте.
+	FormatName = "song-library-backup"
+	// FormatVersion увеличивается при несовместимом изменении структуры архива.
+	FormatVersion = 1
+
+	manifestName = "manifest.json"
+	// restoreBatchSize задает количество строк в одном INSERT при восстановлении.
+	restoreBatchSize = 500
+)
+
+// Table описывает таблицу, попадающую в архив.
+type Table struct {
+	Name    string // Имя таблицы
+	OrderBy string // Колонки для стабильного порядка строк
+	Serial  string // Колонка с последовательностью, которую нужно продвинуть после восстановления
+	Since   uint   // Версия схемы, в которой появилась таблица; в архив более старой схемы она не попадает
+}
+
+// Tables перечисляет таблицы архива в порядке восстановления.
+var Tables = []Table{
+	{Name: "songs", OrderBy: "id", Serial: "id", Since: 1},
+	{Name: "song_details_cache", OrderBy: "cache_key", Since: 2},
+	{Name: "song_changes", OrderBy: "id", Serial: "id", Since: 5},
+	{Name: "webhooks", OrderBy: "id", Serial: "id", Since: 6},
+	{Name: "webhook_deliveries", OrderBy: "id", Serial: "id", Since: 6},
+}
+
+// ExcludedTable описывает таблицу, которая намеренно не попадает в архив.
+type ExcludedTable struct {
+	Name   string `json:"name"`   // Имя таблицы
+	Reason string `json:"reason"` // Почему таблица не сохраняется
+}
+
+// Excluded перечисляет таблицы с данными, которые не сохраняются в архив.
+var Excluded = []ExcludedTable{
+	{
+		Name: "song_changes_state",
+		Reason: "идентификатор журнала изменений создается заново при восстановлении, чтобы токены синхронизации, " +
+			"выданные после создания архива, считались устаревшими, а не совпали с новыми номерами изменений",
+	},
+}
+
+// Manifest описывает содержимое архива.
+type Manifest struct {
+	Format        string          `json:"format"`         // Всегда FormatName
+	FormatVersion int             `json:"format_version"` // Версия структуры архива
+	SchemaVersion uint            `json:"schema_version"` // Версия миграций базы данных на момент создания
+	CreatedAt     time.Time       `json:"created_at"`     // Время создания архива
+	Tables        []TableManifest `json:"tables"`         // Таблицы в порядке восстановления
+	Excluded      []ExcludedTable `json:"excluded"`       // Таблицы, намеренно не сохраненные в архив
+}
+
+// TableManifest описывает файл одной таблицы в архиве.
+type TableManifest struct {
+	Name string `json:"name"` // Имя таблицы
+	File string `json:"file"` // Имя NDJSON-файла в архиве
+	Rows int64  `json:"rows"` // Количество строк
+}
+
+// Create выгружает таблицы из Tables, существующие в схеме версии
+// schemaVersion, в архив, записываемый в w. Таблицы читаются в одной
+// транзакции, поэтому архив согласован.
+func Create(ctx context.Context, db *sql.DB, w io.Writer, schemaVersion uint, logger *slog.Logger) (*Manifest, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	manifest := &Manifest{
+		Format:        FormatName,
+		FormatVersion: FormatVersion,
+		SchemaVersion: schemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		Excluded:      Excluded,
+	}
+
+	// Размер файла в tar нужно знать заранее, поэтому таблицы сначала
+	// выгружаются во временные файлы, а не в память.
+	var dumps []*os.File
+	defer func() {
+		for _, dump := range dumps {
+			dump.Close()
+			os.Remove(dump.Name())
+		}
+	}()
+
+	for _, table := range Tables {
+		if table.Since > schemaVersion {
+			continue
+		}
+		dump, err := os.CreateTemp("", "song-library-"+table.Name+"-*.ndjson")
+		if err != nil {
+			return nil, fmt.Errorf("ошибка создания временного файла: %w", err)
+		}
+		dumps = append(dumps, dump)
+
+		rows, err := dumpTable(ctx, tx, table, dump)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка выгрузки таблицы %s: %w", table.Name, err)
+		}
+		manifest.Tables = append(manifest.Tables, TableManifest{Name: table.Name, File: table.Name + ".ndjson", Rows: rows})
+		logger.InfoContext(ctx, "таблица выгружена", "table", table.Name, "rows", rows)
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(archive, manifestName, int64(len(manifestData)), manifest.CreatedAt, bytes.NewReader(manifestData)); err != nil {
+		return nil, err
+	}
+
+	for i, dump := range dumps {
+		info, err := dump.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := dump.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := writeEntry(archive, manifest.Tables[i].File, info.Size(), manifest.CreatedAt, dump); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("ошибка записи архива: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("ошибка записи архива: %w", err)
+	}
+	return manifest, nil
+}
+
+// dumpTable записывает строки таблицы в w в виде NDJSON и возвращает их количество.
+func dumpTable(ctx context.Context, tx *sql.Tx, table Table, w io.Writer) (int64, error) {
+	query := fmt.Sprintf("SELECT row_to_json(t)::text FROM %s t ORDER BY %s", table.Name, table.OrderBy)
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	buffered := bufio.NewWriter(w)
+	var count int64
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return 0, err
+		}
+		if _, err := buffered.WriteString(line); err != nil {
+			return 0, err
+		}
+		if err := buffered.WriteByte('\n'); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	return count, buffered.Flush()
+}
+
+func writeEntry(archive *tar.Writer, name string, size int64, modTime time.Time, r io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: modTime,
+		Format:  tar.FormatPAX,
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return fmt.Errorf("ошибка записи заголовка %s: %w", name, err)
+	}
+	if _, err := io.Copy(archive, r); err != nil {
+		return fmt.Errorf("ошибка записи %s: %w", name, err)
+	}
+	return nil
+}
+
+// Reader последовательно читает архив: сначала манифест, затем таблицы.
+type Reader struct {
+	gz       *gzip.Reader
+	archive  *tar.Reader
+	Manifest Manifest
+}
+
+// OpenReader открывает архив и читает его манифест.
+func OpenReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("архив не является gzip: %w", err)
+	}
+	archive := tar.NewReader(gz)
+
+	header, err := archive.Next()
+	if err != nil {
+		gz.Close()
+		return nil, fmt.Errorf("ошибка чтения архива: %w", err)
+	}
+	if header.Name != manifestName {
+		gz.Close()
+		return nil, fmt.Errorf("первым файлом архива должен быть %s, найден %s", manifestName, header.Name)
+	}
+
+	reader := &Reader{gz: gz, archive: archive}
+	if err := json.NewDecoder(archive).Decode(&reader.Manifest); err != nil {
+		gz.Close()
+		return nil, fmt.Errorf("ошибка чтения манифеста: %w", err)
+	}
+	if reader.Manifest.Format != FormatName {
+		gz.Close()
+		return nil, fmt.Errorf("неизвестный формат архива: %q", reader.Manifest.Format)
+	}
+	if reader.Manifest.FormatVersion > FormatVersion {
+		gz.Close()
+		return nil, fmt.Errorf("архив создан более новой версией формата (%d > %d)", reader.Manifest.FormatVersion, FormatVersion)
+	}
+	return reader, nil
+}
+
+// Close закрывает архив.
+func (r *Reader) Close() error {
+	return r.gz.Close()
+}
+
+// Restore загружает таблицы архива в базу данных одной транзакцией.
+// Все таблицы из манифеста должны быть пустыми; схема базы должна
+// соответствовать версии Manifest.SchemaVersion.
+func (r *Reader) Restore(ctx context.Context, db *sql.DB, logger *slog.Logger) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	tables := make(map[string]Table, len(Tables))
+	for _, table := range Tables {
+		tables[table.Name] = table
+	}
+	files := make(map[string]TableManifest, len(r.Manifest.Tables))
+	for _, entry := range r.Manifest.Tables {
+		if _, ok := tables[entry.Name]; !ok {
+			return fmt.Errorf("архив содержит неизвестную таблицу %q", entry.Name)
+		}
+		if err := ensureEmpty(ctx, tx, entry.Name); err != nil {
+			return err
+		}
+		files[entry.File] = entry
+	}
+
+	for {
+		header, err := r.archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка чтения архива: %w", err)
+		}
+
+		entry, ok := files[header.Name]
+		if !ok {
+			logger.InfoContext(ctx, "пропущен неизвестный файл архива", "file", header.Name)
+			continue
+		}
+
+		rows, err := restoreTable(ctx, tx, tables[entry.Name], r.archive)
+		if err != nil {
+			return fmt.Errorf("ошибка восстановления таблицы %s: %w", entry.Name, err)
+		}
+		if rows != entry.Rows {
+			return fmt.Errorf("таблица %s: в манифесте %d строк, в архиве %d", entry.Name, entry.Rows, rows)
+		}
+		delete(files, header.Name)
+		logger.InfoContext(ctx, "таблица восстановлена", "table", entry.Name, "rows", rows)
+	}
+
+	for _, entry := range files {
+		return fmt.Errorf("в архиве отсутствует файл %s таблицы %s", entry.File, entry.Name)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+func ensureEmpty(ctx context.Context, tx *sql.Tx, table string) error {
+	var exists bool
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s)", table)
+	if err := tx.QueryRowContext(ctx, query).Scan(&exists); err != nil {
+		return fmt.Errorf("ошибка проверки таблицы %s: %w", table, err)
+	}
+	if exists {
+		return fmt.Errorf("таблица %s не пуста: восстановление возможно только в пустую базу данных", table)
+	}
+	return nil
+}
+
+// restoreTable вставляет NDJSON-строки таблицы пакетами через json_populate_recordset.
+func restoreTable(ctx context.Context, tx *sql.Tx, table Table, r io.Reader) (int64, error) {
+	insert := fmt.Sprintf("INSERT INTO %[1]s SELECT * FROM json_populate_recordset(NULL::%[1]s, $1::json)", table.Name)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+
+	var count int64
+	batch := make([]json.RawMessage, 0, restoreBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		data, err := json.Marshal(batch)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, insert, string(data)); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return 0, fmt.Errorf("строка %d: некорректный JSON", count+1)
+		}
+		batch = append(batch, json.RawMessage(append([]byte(nil), line...)))
+		count++
+		if len(batch) == restoreBatchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	if table.Serial != "" {
+		setval := fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%[1]s', '%[2]s'), COALESCE(MAX(%[2]s), 1), MAX(%[2]s) IS NOT NULL) FROM %[1]s",
+			table.Name, table.Serial,
+		)
+		if _, err := tx.ExecContext(ctx, setval); err != nil {
+			return 0, fmt.Errorf("ошибка обновления последовательности: %w", err)
+		}
+	}
+	return count, nil
+}
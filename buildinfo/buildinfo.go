@@ -0,0 +1,54 @@
+// Package buildinfo хранит сведения о сборке. Значения задаются при сборке:
+//
+//	go build -ldflags "-X song-library/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X song-library/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ) \
+//	  -X song-library/buildinfo.MigrationVersion=3"
+//
+// Без них используются сведения о VCS, которые go build встраивает в бинарный
+// файл (хеш и время последнего коммита), а версия миграций берется из папки
+// миграций.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+)
+
+// Значения, задаваемые через -ldflags -X.
+var (
+	Commit           string // Хеш коммита git
+	BuildTime        string // Время сборки в формате RFC 3339
+	MigrationVersion string // Версия схемы базы данных, которую ожидает сборка
+)
+
+// Info описывает сборку приложения.
+type Info struct {
+	Commit           string `json:"commit" example:"2dda633f0c1e"`
+	BuildTime        string `json:"build_time" example:"2026-10-19T06:00:00Z"`
+	Modified         bool   `json:"modified" example:"false"`
+	GoVersion        string `json:"go_version" example:"go1.23.2"`
+	MigrationVersion uint   `json:"migration_version" example:"3"`
+}
+
+// Get возвращает сведения о сборке. migrationVersion — версия миграций,
+// которая используется, если MigrationVersion не задана при сборке.
+func Get(migrationVersion uint) Info {
+	if version, err := strconv.ParseUint(MigrationVersion, 10, 0); err == nil {
+		migrationVersion = uint(version)
+	}
+	info := Info{Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version(), MigrationVersion: migrationVersion}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			case setting.Key == "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
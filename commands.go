@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"song-library/backup"
+	"song-library/config"
+	"song-library/domain"
+	"song-library/migrations"
+	"song-library/songio"
+	"strconv"
+	"time"
+)
+
+// runCommand выполняет подкоманду CLI с аргументами args. ctx отменяется по сигналу прерывания.
+func runCommand(ctx context.Context, cfg *config.Config, name string, args []string) error {
+	switch name {
+	case "import":
+		return runImportCommand(ctx, cfg, args)
+	case "backup":
+		return runBackupCommand(ctx, cfg, args)
+	case "restore":
+		return runRestoreCommand(ctx, cfg, args)
+	case "migrate":
+		return runMigrateCommand(ctx, cfg, args)
+	default:
+		return fmt.Errorf("неизвестная команда %q (доступны: import, backup, restore, migrate, config)", name)
+	}
+}
+
+// runImportCommand импортирует песни из файла: import [-format csv|ndjson] [-dry-run] [-batch-size N] [-enrich] <файл|->
+func runImportCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	formatName := flags.String("format", "", "формат файла: csv или ndjson (по умолчанию по расширению)")
+	dryRun := flags.Bool("dry-run", false, "проверить файл без сохранения")
+	batchSize := flags.Int("batch-size", 0, "количество строк в одной транзакции")
+	enrich := flags.Bool("enrich", false, "запрашивать недостающие детали во внешнем API")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("использование: import [-format csv|ndjson] [-dry-run] [-batch-size N] [-enrich] <файл|->")
+	}
+	path := flags.Arg(0)
+
+	var format songio.Format
+	switch {
+	case *formatName != "":
+		parsed, err := songio.ParseFormat(*formatName)
+		if err != nil {
+			return err
+		}
+		format = parsed
+	default:
+		detected, ok := songio.FormatFromPath(path)
+		if !ok {
+			return errors.New("не удалось определить формат по расширению файла, укажите -format")
+		}
+		format = detected
+	}
+
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	reader, err := songio.NewReader(input, format)
+	if err != nil {
+		return err
+	}
+
+	app := newApplication(ctx, cfg)
+	defer app.Close()
+
+	report, err := app.songService.ImportSongs(ctx, reader, domain.ImportOptions{
+		DryRun:    *dryRun,
+		BatchSize: *batchSize,
+		Enrich:    *enrich,
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// runBackupCommand создает полный архив библиотеки: backup [-o файл|-]
+func runBackupCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := flags.String("o", "", "файл архива (по умолчанию song-library-<время>.tar.gz, - для stdout)")
+	flags.Usage = func() {
+		out := flags.Output()
+		fmt.Fprintln(out, "использование: backup [-o файл|-]")
+		flags.PrintDefaults()
+		fmt.Fprintln(out, "\nТаблицы архива:")
+		for _, table := range backup.Tables {
+			fmt.Fprintf(out, "  %s\n", table.Name)
+		}
+		fmt.Fprintln(out, "Не сохраняются:")
+		for _, table := range backup.Excluded {
+			fmt.Fprintf(out, "  %s: %s\n", table.Name, table.Reason)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	dbURL := cfg.DBURL
+	if !isPostgresURL(dbURL) {
+		return errors.New("резервное копирование доступно только для PostgreSQL, базу SQLite достаточно скопировать как файл")
+	}
+	logger := slog.Default()
+	db, err := openDatabase(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	version, dirty, err := migrations.SchemaVersion(db, dbURL)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("схема базы данных в состоянии dirty (версия %d), архив не создан", version)
+	}
+
+	path := *output
+	if path == "" {
+		path = fmt.Sprintf("song-library-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	var out io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	manifest, err := backup.Create(ctx, db, out, version, logger)
+	if err != nil {
+		if path != "-" {
+			os.Remove(path)
+		}
+		return err
+	}
+
+	logger.InfoContext(ctx, "архив создан", "file", path, "schema_version", manifest.SchemaVersion, "tables", len(manifest.Tables))
+	return nil
+}
+
+// runRestoreCommand восстанавливает архив в пустую базу данных: restore <файл|->
+//
+// База сначала мигрируется до версии схемы архива, затем загружаются данные,
+// и после этого применяются оставшиеся миграции. Архив более новой схемы,
+// чем известна этой сборке, не восстанавливается.
+func runRestoreCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("использование: restore <файл|->")
+	}
+
+	var input io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	archive, err := backup.OpenReader(input)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	archiveVersion := archive.Manifest.SchemaVersion
+
+	dbURL := cfg.DBURL
+	if !isPostgresURL(dbURL) {
+		return errors.New("восстановление доступно только для PostgreSQL")
+	}
+
+	latest, err := migrations.LatestVersion(dbURL)
+	if err != nil {
+		return err
+	}
+	if archiveVersion > latest {
+		return fmt.Errorf("архив создан на схеме версии %d, а эта сборка знает только версии до %d", archiveVersion, latest)
+	}
+
+	logger := slog.Default()
+	db, err := openDatabase(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	current, dirty, err := migrations.SchemaVersion(db, dbURL)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("схема базы данных в состоянии dirty (версия %d)", current)
+	}
+	if current > archiveVersion {
+		return fmt.Errorf("схема базы данных (версия %d) новее схемы архива (версия %d): восстановите архив в новую базу данных", current, archiveVersion)
+	}
+
+	if err := migrations.MigrateTo(db, dbURL, archiveVersion); err != nil {
+		return err
+	}
+
+	if err := archive.Restore(ctx, db, logger); err != nil {
+		return err
+	}
+
+	if archiveVersion < latest {
+		logger.InfoContext(ctx, "обновление схемы после восстановления", "from", archiveVersion, "to", latest)
+		if err := migrations.MigrateTo(db, dbURL, latest); err != nil {
+			return err
+		}
+	}
+
+	logger.InfoContext(ctx, "архив восстановлен", "schema_version", latest, "tables", len(archive.Manifest.Tables))
+	return nil
+}
+
+// runConfigCommand выполняет config print: выводит итоговые настройки с
+// источником каждого значения, скрывая пароли. loadErr — ошибка загрузки
+// настроек; она выводится после настроек и завершает команду с ошибкой.
+func runConfigCommand(cfg *config.Config, loadErr error, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return errors.New("использование: config print")
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		return err
+	}
+	return loadErr
+}
+
+// migrateUsage описывает аргументы команды migrate; выводится по migrate -h.
+const migrateUsage = `использование: migrate up [N] | down [N] | down -all | goto V | version | force V
+  up [N]     применить все или N следующих миграций
+  down [N]   откатить последнюю или N последних миграций
+  down -all  откатить все миграции
+  goto V     перейти на версию V вверх или вниз
+  version    показать текущую версию схемы
+  force V    записать версию V и снять признак dirty без выполнения миграций`
+
+// runMigrateCommand управляет схемой базы данных:
+//
+//	migrate up [N]   — применить все или N следующих миграций;
+//	migrate down [N] — откатить последнюю или N последних миграций;
+//	migrate down -all — откатить все миграции;
+//	migrate goto V   — перейти на версию V вверх или вниз;
+//	migrate version  — показать текущую версию схемы;
+//	migrate force V  — записать версию V и снять признак dirty без выполнения миграций.
+func runMigrateCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	action, args := args[0], args[1:]
+	if action == "-h" || action == "-help" || action == "--help" {
+		fmt.Println(migrateUsage)
+		return nil
+	}
+
+	dbURL := cfg.DBURL
+	if isMemoryURL(dbURL) {
+		return errors.New("хранилищу в памяти миграции не нужны")
+	}
+	logger := slog.Default()
+	db, err := openDatabase(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch {
+	case action == "up" && len(args) == 0:
+		err = migrations.RunMigrations(db, dbURL)
+	case action == "up" && len(args) == 1:
+		var n int
+		if n, err = positiveArg(args[0]); err == nil {
+			err = migrations.Steps(db, dbURL, n)
+		}
+	case action == "down" && len(args) == 0:
+		// Без аргументов откатывается только последняя миграция, чтобы
+		// случайный вызов не удалил схему; полный откат требует явного -all
+		err = migrations.Steps(db, dbURL, -1)
+	case action == "down" && len(args) == 1 && args[0] == "-all":
+		err = migrations.RollbackAll(db, dbURL)
+	case action == "down" && len(args) == 1:
+		var n int
+		if n, err = positiveArg(args[0]); err == nil {
+			err = migrations.Steps(db, dbURL, -n)
+		}
+	case action == "goto" && len(args) == 1:
+		var version int
+		if version, err = positiveArg(args[0]); err == nil {
+			err = migrations.MigrateTo(db, dbURL, uint(version))
+		}
+	case action == "force" && len(args) == 1:
+		var version int
+		if version, err = strconv.Atoi(args[0]); err != nil || version < -1 {
+			err = fmt.Errorf("версия должна быть целым числом не меньше -1, получено %q", args[0])
+		} else {
+			err = migrations.Force(db, dbURL, version)
+		}
+	case action == "version" && len(args) == 0:
+	default:
+		return errors.New(migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := migrations.SchemaVersion(db, dbURL)
+	if err != nil {
+		return err
+	}
+	latest, err := migrations.LatestVersion(dbURL)
+	if err != nil {
+		return err
+	}
+	state := "ok"
+	if dirty {
+		state = "dirty"
+	}
+	fmt.Printf("версия схемы: %d (%s), последняя известная сборке: %d\n", version, state, latest)
+	return nil
+}
+
+// positiveArg разбирает положительное целое число из аргумента команды.
+func positiveArg(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("ожидается положительное целое число, получено %q", arg)
+	}
+	return n, nil
+}
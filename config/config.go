@@ -0,0 +1,214 @@
+// Package config загружает настройки приложения из нескольких источников.
+//
+// Источники в порядке возрастания приоритета:
+//
+//  1. значения по умолчанию (тег default);
+//  2. файл YAML или TOML, путь к которому задает CONFIG_FILE;
+//  3. файл .env в рабочем каталоге;
+//  4. переменные окружения.
+//
+// Ключи совпадают с именами переменных окружения (тег env). В файле YAML или
+// TOML они записываются в нижнем регистре, вложенные секции склеиваются через
+// подчеркивание: секция details_cache с ключом ttl задает DETAILS_CACHE_TTL.
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config содержит настройки приложения.
+type Config struct {
+	AppPort         int    `env:"APP_PORT" default:"8080"`             // Порт HTTP-сервера
+	DBURL           string `env:"DB_URL" secret:"true"`                // postgres://, sqlite:// или memory://
+	APIBaseURL      string `env:"API_BASE_URL"`                        // Адрес внешнего API деталей песен
+	DefaultLanguage string `env:"DEFAULT_LANGUAGE" default:"ru"`       // Язык сообщений без Accept-Language
+	LogLevel        string `env:"LOG_LEVEL" default:"info"`            // debug, info, warn или error
+	TracesExporter  string `env:"OTEL_TRACES_EXPORTER" default:"none"` // otlp, stdout или none
+	ConfigFile      string `env:"CONFIG_FILE"`                         // Файл настроек YAML или TOML
+
+	DBMaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" default:"10"`     // Наибольшее число открытых соединений
+	DBMaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" default:"5"`      // Наибольшее число простаивающих соединений
+	DBConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" default:"30m"` // Время жизни соединения, 0 — без ограничения
+	DBConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" default:"5m"` // Время простоя соединения, 0 — без ограничения
+	DBConnectTimeout  time.Duration `env:"DB_CONNECT_TIMEOUT" default:"30s"`   // Сколько ждать доступности базы данных при запуске
+	DBAutoMigrate     bool          `env:"DB_AUTO_MIGRATE" default:"true"`     // Применять новые миграции при запуске сервера
+
+	QueryTimeout  time.Duration            `env:"QUERY_TIMEOUT" default:"5s"` // Предельное время обработки запроса
+	RouteTimeouts map[string]time.Duration `env:"QUERY_TIMEOUT_*"`            // Предельное время для отдельных маршрутов
+
+	DetailsCacheSize        int           `env:"DETAILS_CACHE_SIZE" default:"1000"`        // Вместимость кэша деталей песен
+	DetailsCacheTTL         time.Duration `env:"DETAILS_CACHE_TTL" default:"24h"`          // Время жизни успешного ответа
+	DetailsCacheNegativeTTL time.Duration `env:"DETAILS_CACHE_NEGATIVE_TTL" default:"10m"` // Время жизни ответа "не найдена"
+	DetailsCachePersistent  bool          `env:"DETAILS_CACHE_PERSISTENT" default:"false"` // Хранить кэш в базе данных
+
+	ChangesRetention     time.Duration `env:"CHANGES_RETENTION" default:"720h"`    // Сколько хранить журнал изменений для /changes
+	ChangesPruneInterval time.Duration `env:"CHANGES_PRUNE_INTERVAL" default:"1h"` // Как часто удалять устаревшие изменения
+	EventsPollInterval   time.Duration `env:"EVENTS_POLL_INTERVAL" default:"1s"`   // Как часто /events читает журнал без LISTEN/NOTIFY (SQLite, память)
+	EventsHeartbeat      time.Duration `env:"EVENTS_HEARTBEAT" default:"15s"`      // Пауза между комментариями в /events, пока нет изменений
+
+	WebhookTimeout      time.Duration `env:"WEBHOOK_TIMEOUT" default:"10s"`      // Предельное время запроса к подписчику
+	WebhookMaxAttempts  int           `env:"WEBHOOK_MAX_ATTEMPTS" default:"10"`  // Попыток доставки до признания ее неудачной
+	WebhookRetryMin     time.Duration `env:"WEBHOOK_RETRY_MIN" default:"10s"`    // Пауза перед первой повторной попыткой
+	WebhookRetryMax     time.Duration `env:"WEBHOOK_RETRY_MAX" default:"1h"`     // Наибольшая пауза между попытками
+	WebhookPollInterval time.Duration `env:"WEBHOOK_POLL_INTERVAL" default:"1s"` // Как часто проверять очередь доставок
+	WebhookRetention    time.Duration `env:"WEBHOOK_RETENTION" default:"720h"`   // Сколько хранить завершенные доставки в журнале
+
+	ReadinessCheckUpstream bool          `env:"READINESS_CHECK_UPSTREAM" default:"false"` // Проверять внешний API в /readyz
+	ShutdownDelay          time.Duration `env:"SHUTDOWN_DELAY" default:"0s"`              // Пауза между отказом /readyz и закрытием порта
+	ShutdownGracePeriod    time.Duration `env:"SHUTDOWN_GRACE_PERIOD" default:"30s"`      // Время на завершение запросов и фоновых задач
+
+	origins map[string]Source // Источник значения каждой заданной настройки
+}
+
+// Routes перечисляет маршруты, для которых можно задать QUERY_TIMEOUT_<МАРШРУТ>.
+var Routes = []string{"LIBRARY", "SONG_TEXT", "SONG_DELETE", "SONG_UPDATE", "SONG_CREATE", "SONG_BATCH", "IMPORT", "EXPORT", "CHANGES", "SONG_ENRICH", "WEBHOOKS"}
+
+// RouteTimeout возвращает предельное время обработки маршрута route или fallback,
+// если для маршрута оно не задано.
+func (c *Config) RouteTimeout(route string, fallback time.Duration) time.Duration {
+	if timeout, ok := c.RouteTimeouts[route]; ok {
+		return timeout
+	}
+	return fallback
+}
+
+// Source обозначает источник значения настройки.
+type Source string
+
+// Источники значений.
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceDotEnv  Source = ".env"
+	SourceEnv     Source = "env"
+)
+
+// Error перечисляет все ошибки загрузки и проверки настроек.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "некорректная конфигурация:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+func (e *Error) add(format string, args ...any) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+func (e *Error) err() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
+
+// field описывает настройку: поле Config и ключ.
+type field struct {
+	index    int
+	key      string // Имя переменной; для карт — префикс без *
+	prefix   bool   // Поле-карта, ключи которой добавляются к префиксу
+	fallback string
+	secret   bool
+}
+
+// fields возвращает описания настроек Config в порядке объявления.
+func fields() []field {
+	t := reflect.TypeOf(Config{})
+	result := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := f.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		prefix := strings.HasSuffix(key, "*")
+		result = append(result, field{
+			index:    i,
+			key:      strings.TrimSuffix(key, "*"),
+			prefix:   prefix,
+			fallback: f.Tag.Get("default"),
+			secret:   f.Tag.Get("secret") == "true",
+		})
+	}
+	return result
+}
+
+// lookup находит значение настройки key в источниках по приоритету.
+func lookup(sources []layer, key string) (string, Source, bool) {
+	for i := len(sources) - 1; i >= 0; i-- {
+		if value, ok := sources[i].values[key]; ok {
+			return value, sources[i].source, true
+		}
+	}
+	return "", "", false
+}
+
+// prefixed собирает из источников значения ключей с префиксом prefix;
+// старшие источники перекрывают младшие.
+func prefixed(sources []layer, prefix string) map[string]layerValue {
+	values := make(map[string]layerValue)
+	for _, source := range sources {
+		for key, value := range source.values {
+			if suffix, ok := strings.CutPrefix(key, prefix); ok && suffix != "" {
+				values[suffix] = layerValue{value: value, source: source.source}
+			}
+		}
+	}
+	return values
+}
+
+// layer — значения настроек из одного источника.
+type layer struct {
+	source Source
+	values map[string]string
+}
+
+type layerValue struct {
+	value  string
+	source Source
+}
+
+// set разбирает строку value в поле target.
+func set(target reflect.Value, value string) error {
+	switch target.Interface().(type) {
+	case time.Duration:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("ожидается длительность вида 5s или 10m, получено %q", value)
+		}
+		target.SetInt(int64(duration))
+	case int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("ожидается целое число, получено %q", value)
+		}
+		target.SetInt(int64(number))
+	case bool:
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("ожидается true или false, получено %q", value)
+		}
+		target.SetBool(flag)
+	case string:
+		target.SetString(value)
+	default:
+		panic(fmt.Sprintf("config: неподдерживаемый тип поля %s", target.Type()))
+	}
+	return nil
+}
+
+// knownKey сообщает, что key — ключ одной из настроек.
+func knownKey(key string) bool {
+	return slices.ContainsFunc(fields(), func(f field) bool {
+		if f.prefix {
+			return strings.HasPrefix(key, f.key)
+		}
+		return key == f.key
+	})
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// readFile читает файл настроек YAML или TOML и возвращает значения по
+// ключам-именам переменных. Неизвестные ключи считаются ошибкой, чтобы
+// опечатка в файле не оставалась незамеченной.
+func readFile(path string, problems *Error) map[string]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		problems.add("CONFIG_FILE: %v", err)
+		return nil
+	}
+
+	var document map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	default:
+		problems.add("CONFIG_FILE: неподдерживаемое расширение %q, допустимы .yaml, .yml и .toml", filepath.Ext(path))
+		return nil
+	}
+	if err != nil {
+		problems.add("%s: %v", path, err)
+		return nil
+	}
+
+	values := make(map[string]string)
+	flatten("", document, values, path, problems)
+	return values
+}
+
+// flatten переносит значения документа в values, склеивая имена вложенных
+// секций с ключами через подчеркивание.
+func flatten(prefix string, document map[string]any, values map[string]string, path string, problems *Error) {
+	for name, value := range document {
+		key := strings.ToUpper(prefix + name)
+		switch value := value.(type) {
+		case map[string]any:
+			flatten(key+"_", value, values, path, problems)
+		case []any:
+			problems.add("%s: ключ %s: списки не поддерживаются", path, strings.ToLower(key))
+		default:
+			if !knownKey(key) {
+				problems.add("%s: неизвестный ключ %s", path, strings.ToLower(key))
+				continue
+			}
+			values[key] = fmt.Sprint(value)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// dotEnvPath — файл .env, который читается из рабочего каталога.
+const dotEnvPath = ".env"
+
+// Load загружает настройки из всех источников и проверяет их. При ошибке
+// возвращается заполненный насколько возможно Config и *Error со всеми
+// найденными проблемами.
+//
+// Переменные из .env, не заданные в окружении, добавляются в окружение
+// процесса: их читают библиотеки, например OpenTelemetry (OTEL_*).
+func Load() (*Config, error) {
+	problems := &Error{}
+
+	env := environ()
+	dotEnv, err := readDotEnv(dotEnvPath)
+	if err != nil {
+		problems.add("%s: %v", dotEnvPath, err)
+	}
+
+	var file map[string]string
+	path := env["CONFIG_FILE"]
+	if path == "" {
+		path = dotEnv["CONFIG_FILE"]
+	}
+	if path != "" {
+		file = readFile(path, problems)
+	}
+
+	sources := []layer{
+		{source: SourceDefault, values: defaults()},
+		{source: SourceFile, values: file},
+		{source: SourceDotEnv, values: dotEnv},
+		{source: SourceEnv, values: env},
+	}
+	cfg := &Config{origins: make(map[string]Source)}
+	cfg.fill(sources, problems)
+	cfg.validate(problems)
+
+	for key, value := range dotEnv {
+		if _, ok := os.LookupEnv(key); !ok {
+			os.Setenv(key, value)
+		}
+	}
+	return cfg, problems.err()
+}
+
+// fill заполняет поля значениями из источников.
+func (c *Config) fill(sources []layer, problems *Error) {
+	value := reflect.ValueOf(c).Elem()
+	for _, f := range fields() {
+		target := value.Field(f.index)
+
+		if f.prefix {
+			entries := reflect.MakeMap(target.Type())
+			for suffix, entry := range prefixed(sources, f.key) {
+				item := reflect.New(target.Type().Elem()).Elem()
+				if err := set(item, entry.value); err != nil {
+					problems.add("%s%s: %v", f.key, suffix, err)
+					continue
+				}
+				entries.SetMapIndex(reflect.ValueOf(suffix), item)
+				c.origins[f.key+suffix] = entry.source
+			}
+			target.Set(entries)
+			continue
+		}
+
+		raw, source, ok := lookup(sources, f.key)
+		if !ok {
+			continue
+		}
+		if err := set(target, raw); err != nil {
+			problems.add("%s: %v", f.key, err)
+			continue
+		}
+		c.origins[f.key] = source
+	}
+}
+
+// defaults возвращает значения по умолчанию из тегов default.
+func defaults() map[string]string {
+	values := make(map[string]string)
+	for _, f := range fields() {
+		if f.fallback != "" {
+			values[f.key] = f.fallback
+		}
+	}
+	return values
+}
+
+// environ возвращает непустые переменные окружения процесса.
+func environ() map[string]string {
+	values := make(map[string]string)
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		if value != "" {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// readDotEnv читает непустые переменные из файла .env. Отсутствие файла не ошибка.
+func readDotEnv(path string) (map[string]string, error) {
+	values, err := godotenv.Read(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range values {
+		if value == "" {
+			delete(values, key)
+		}
+	}
+	return values, nil
+}
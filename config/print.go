@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"time"
+
+	"song-library/logging"
+)
+
+// Print выводит итоговые значения настроек в формате .env с источником
+// каждого значения. Пароли в секретных настройках скрываются.
+func (c *Config) Print(w io.Writer) error {
+	value := reflect.ValueOf(c).Elem()
+	for _, f := range fields() {
+		target := value.Field(f.index)
+
+		if f.prefix {
+			routes := make([]string, 0, target.Len())
+			for _, key := range target.MapKeys() {
+				routes = append(routes, key.String())
+			}
+			slices.Sort(routes)
+			for _, route := range routes {
+				entry := target.MapIndex(reflect.ValueOf(route)).Interface()
+				if err := c.printValue(w, f.key+route, entry, f.secret); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := c.printValue(w, f.key, target.Interface(), f.secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printValue выводит одну настройку.
+func (c *Config) printValue(w io.Writer, key string, value any, secret bool) error {
+	text := fmt.Sprint(value)
+	if duration, ok := value.(time.Duration); ok {
+		text = duration.String()
+	}
+	if secret {
+		text = logging.RedactURL(text)
+	}
+
+	source, ok := c.origins[key]
+	if !ok {
+		source = "unset"
+	}
+	_, err := fmt.Fprintf(w, "%s=%s  # %s\n", key, text, source)
+	return err
+}
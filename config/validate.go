@@ -0,0 +1,107 @@
+package config
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"song-library/i18n"
+	"song-library/logging"
+	"song-library/tracing"
+)
+
+// dbSchemes перечисляет допустимые схемы DB_URL.
+var dbSchemes = []string{"postgres", "postgresql", "sqlite", "memory"}
+
+// validate проверяет значения настроек и добавляет в problems все найденные ошибки.
+func (c *Config) validate(problems *Error) {
+	if c.AppPort < 1 || c.AppPort > 65535 {
+		problems.add("APP_PORT: порт должен быть от 1 до 65535, получено %d", c.AppPort)
+	}
+
+	switch parsed, err := url.Parse(c.DBURL); {
+	case c.DBURL == "":
+		problems.add("DB_URL: обязательная настройка, допустимы схемы %s", strings.Join(dbSchemes, ", "))
+	case err != nil:
+		problems.add("DB_URL: некорректный URL: %v", logging.RedactURL(err.Error()))
+	case !slices.Contains(dbSchemes, parsed.Scheme):
+		problems.add("DB_URL: неподдерживаемая схема %q, допустимы %s", parsed.Scheme, strings.Join(dbSchemes, ", "))
+	case parsed.Scheme == "sqlite" && strings.TrimPrefix(c.DBURL, "sqlite://") == "":
+		problems.add("DB_URL: не указан путь к файлу SQLite")
+	}
+
+	if c.DBMaxOpenConns < 1 {
+		problems.add("DB_MAX_OPEN_CONNS: должно быть хотя бы одно соединение, получено %d", c.DBMaxOpenConns)
+	}
+	if c.DBMaxIdleConns < 0 || c.DBMaxIdleConns > c.DBMaxOpenConns {
+		problems.add("DB_MAX_IDLE_CONNS: ожидается число от 0 до DB_MAX_OPEN_CONNS (%d), получено %d", c.DBMaxOpenConns, c.DBMaxIdleConns)
+	}
+	nonNegative(problems, "DB_CONN_MAX_LIFETIME", c.DBConnMaxLifetime)
+	nonNegative(problems, "DB_CONN_MAX_IDLE_TIME", c.DBConnMaxIdleTime)
+	positive(problems, "DB_CONNECT_TIMEOUT", c.DBConnectTimeout)
+
+	switch parsed, err := url.Parse(c.APIBaseURL); {
+	case c.APIBaseURL == "":
+		problems.add("API_BASE_URL: обязательная настройка")
+	case err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "":
+		problems.add("API_BASE_URL: ожидается ссылка http или https, получено %q", c.APIBaseURL)
+	}
+
+	if _, err := i18n.ParseLanguage(c.DefaultLanguage); err != nil {
+		problems.add("DEFAULT_LANGUAGE: %v", err)
+	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		problems.add("LOG_LEVEL: %v", err)
+	}
+	if !slices.Contains(tracing.Exporters, strings.ToLower(c.TracesExporter)) {
+		problems.add("OTEL_TRACES_EXPORTER: неизвестный экспортер %q, допустимы %s", c.TracesExporter, strings.Join(tracing.Exporters, ", "))
+	}
+
+	nonNegative(problems, "QUERY_TIMEOUT", c.QueryTimeout)
+	for route, timeout := range c.RouteTimeouts {
+		if !slices.Contains(Routes, route) {
+			problems.add("QUERY_TIMEOUT_%s: неизвестный маршрут, допустимы %s", route, strings.Join(Routes, ", "))
+			continue
+		}
+		nonNegative(problems, "QUERY_TIMEOUT_"+route, timeout)
+	}
+
+	if c.DetailsCacheSize < 1 {
+		problems.add("DETAILS_CACHE_SIZE: размер кэша должен быть положительным, получено %d", c.DetailsCacheSize)
+	}
+	positive(problems, "DETAILS_CACHE_TTL", c.DetailsCacheTTL)
+	nonNegative(problems, "DETAILS_CACHE_NEGATIVE_TTL", c.DetailsCacheNegativeTTL)
+
+	positive(problems, "CHANGES_RETENTION", c.ChangesRetention)
+	positive(problems, "CHANGES_PRUNE_INTERVAL", c.ChangesPruneInterval)
+	positive(problems, "EVENTS_POLL_INTERVAL", c.EventsPollInterval)
+	positive(problems, "EVENTS_HEARTBEAT", c.EventsHeartbeat)
+
+	positive(problems, "WEBHOOK_TIMEOUT", c.WebhookTimeout)
+	if c.WebhookMaxAttempts < 1 {
+		problems.add("WEBHOOK_MAX_ATTEMPTS: количество попыток должно быть положительным, получено %d", c.WebhookMaxAttempts)
+	}
+	positive(problems, "WEBHOOK_RETRY_MIN", c.WebhookRetryMin)
+	positive(problems, "WEBHOOK_RETRY_MAX", c.WebhookRetryMax)
+	if c.WebhookRetryMin > c.WebhookRetryMax {
+		problems.add("WEBHOOK_RETRY_MIN: пауза %s больше WEBHOOK_RETRY_MAX (%s)", c.WebhookRetryMin, c.WebhookRetryMax)
+	}
+	positive(problems, "WEBHOOK_POLL_INTERVAL", c.WebhookPollInterval)
+	positive(problems, "WEBHOOK_RETENTION", c.WebhookRetention)
+
+	nonNegative(problems, "SHUTDOWN_DELAY", c.ShutdownDelay)
+	positive(problems, "SHUTDOWN_GRACE_PERIOD", c.ShutdownGracePeriod)
+}
+
+func positive(problems *Error, key string, value time.Duration) {
+	if value <= 0 {
+		problems.add("%s: длительность должна быть положительной, получено %s", key, value)
+	}
+}
+
+func nonNegative(problems *Error, key string, value time.Duration) {
+	if value < 0 {
+		problems.add("%s: длительность не может быть отрицательной, получено %s", key, value)
+	}
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"song-library/domain"
+	"song-library/i18n"
+	"song-library/problem"
+)
+
+// ErrorCatalogHandler возвращает каталог кодов ошибок.
+//
+//	@Summary		Каталог кодов ошибок
+//	@Description	Все коды, которые могут прийти в поле code ответа application/problem+json, с HTTP-статусами.
+//	@Description	Поле type ответа об ошибке ссылается на описание кода: /errors/{code}.
+//	@Tags			Errors
+//	@Produce		json
+//	@Success		200	{array}	problem.CodeInfo
+//	@Router			/errors [get]
+func ErrorCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	catalog := make([]problem.CodeInfo, 0, len(domain.ErrorCodes))
+	for _, code := range domain.ErrorCodes {
+		info, _ := problem.Lookup(code, i18n.FromContext(r.Context()))
+		catalog = append(catalog, info)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(catalog)
+}
+
+// ErrorCodeHandler возвращает описание одного кода ошибки.
+//
+//	@Summary		Описание кода ошибки
+//	@Description	Описание кода ошибки, на которое ссылается поле type ответа application/problem+json.
+//	@Tags			Errors
+//	@Produce		json
+//	@Param			code	path		string	true	"Код ошибки"
+//	@Success		200		{object}	problem.CodeInfo
+//	@Failure		404		{object}	problem.Problem	"Код не найден"
+//	@Router			/errors/{code} [get]
+func ErrorCodeHandler(w http.ResponseWriter, r *http.Request) {
+	info, ok := problem.Lookup(domain.ErrorCode(r.PathValue("code")), i18n.FromContext(r.Context()))
+	if !ok {
+		problem.Write(w, r, "op.get_error_code", domain.NewNotFoundError(domain.CodeNotFound, "неизвестный код ошибки"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"song-library/problem"
+	"song-library/service"
+)
+
+// eventRetry — пауза перед переподключением, которую поток событий
+// сообщает клиенту (поле retry).
+const eventRetry = 3 * time.Second
+
+// EventController отдает поток событий об изменениях песен (Server-Sent Events).
+type EventController struct {
+	stream    *service.ChangeStream
+	heartbeat time.Duration
+}
+
+// NewEventController создает новый EventController. Если изменений нет,
+// каждые heartbeat в поток отправляется комментарий, чтобы прокси не
+// закрывали соединение.
+func NewEventController(stream *service.ChangeStream, heartbeat time.Duration) *EventController {
+	return &EventController{stream: stream, heartbeat: heartbeat}
+}
+
+// EventsHandler отправляет изменения песен по мере их фиксации.
+//
+//	@Summary		Поток изменений библиотеки
+//	@Description	Server-Sent Events с изменениями песен в порядке фиксации: события song.created, song.updated и song.deleted с данными domain.SongChange.
+//	@Description	ID события — токен синхронизации, как в поле next ответа /changes. После разрыва EventSource передает его в заголовке Last-Event-ID, и поток продолжается без пропусков.
+//	@Description	Событие reset означает, что продолжить поток без пропусков нельзя (изменения удалены по CHANGES_RETENTION или база восстановлена из архива): клиент загружает библиотеку заново, а поток продолжается с ID события reset.
+//	@Description	Параметр group (можно несколько) оставляет только изменения песен этих групп, без учета регистра.
+//	@Tags			Songs
+//	@Produce		text/event-stream
+//	@Param			group			query		[]string	false	"Группы песен"	collectionFormat(multi)
+//	@Param			since			query		string		false	"Токен, после которого начать поток; по умолчанию — с текущего момента"
+//	@Param			Last-Event-ID	header		string		false	"ID последнего полученного события; важнее since"
+//	@Success		200				{object}	domain.SongChange	"Поток событий"
+//	@Failure		400				{object}	problem.Problem				"Некорректный токен"
+//	@Failure		500				{object}	problem.Problem				"Ошибка подписки на события"
+//	@Router			/events [get]
+func (c *EventController) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	since := r.Header.Get("Last-Event-ID")
+	if since == "" {
+		since = r.URL.Query().Get("since")
+	}
+	groups := r.URL.Query()["group"]
+
+	sub, err := c.stream.Subscribe(r.Context(), since, groups)
+	reset := errors.Is(err, service.ErrSyncTokenExpired)
+	if reset {
+		sub, err = c.stream.Subscribe(r.Context(), "", groups)
+	}
+	if err != nil {
+		problem.Write(w, r, "op.subscribe_events", err)
+		return
+	}
+	defer func() { sub.Close() }()
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no") // Отключает буферизацию ответа в nginx
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventRetry.Milliseconds())
+
+	rc := http.NewResponseController(w)
+	for {
+		if reset {
+			writeEvent(w, "reset", sub.LastEventID(), map[string]string{"next": sub.LastEventID()})
+			reset = false
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		change, err := sub.Next(r.Context(), c.heartbeat)
+		switch {
+		case errors.Is(err, service.ErrSyncTokenExpired):
+			sub.Close()
+			next, err := c.stream.Subscribe(r.Context(), "", groups)
+			if err != nil {
+				return
+			}
+			sub, reset = next, true
+		case err != nil:
+			// Клиент отключился или приложение останавливается
+			return
+		case change == nil:
+			// Поле id без данных не создает событие, но обновляет Last-Event-ID
+			// клиента: изменения других групп тоже продвигают его
+			fmt.Fprintf(w, ": ping\nid: %s\n\n", sub.LastEventID())
+		default:
+			writeEvent(w, "song."+change.Op, sub.LastEventID(), change)
+		}
+	}
+}
+
+// writeEvent записывает событие name с ID id и данными data в формате JSON.
+func writeEvent(w io.Writer, name, id string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		// Данные событий всегда сериализуются; ошибка означает ошибку программиста
+		panic(err)
+	}
+	fmt.Fprintf(w, "event: %s\nid: %s\ndata: %s\n\n", name, id, payload)
+}
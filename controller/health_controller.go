@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"song-library/buildinfo"
+)
+
+// Состояния проверок готовности.
+const (
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+)
+
+// checkTimeout ограничивает время одной проверки готовности.
+const checkTimeout = 2 * time.Second
+
+// ReadinessCheck — проверка одной зависимости, без которой сервис не может
+// обслуживать запросы.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthStatus описывает результат проверки.
+type HealthStatus struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck описывает результат одной проверки готовности.
+type HealthCheck struct {
+	Status   string `json:"status" example:"ok"`
+	Error    string `json:"error,omitempty" example:"dial tcp: connection refused"`
+	Duration string `json:"duration,omitempty" example:"1.2ms"`
+}
+
+// HealthController отвечает на проверки живости и готовности и сообщает сведения о сборке.
+type HealthController struct {
+	checks       []ReadinessCheck
+	build        buildinfo.Info
+	shuttingDown atomic.Bool
+}
+
+// NewHealthController создает HealthController с проверками готовности checks.
+func NewHealthController(build buildinfo.Info, checks ...ReadinessCheck) *HealthController {
+	return &HealthController{checks: checks, build: build}
+}
+
+// BeginShutdown переводит проверку готовности в состояние отказа: балансировщик
+// перестает направлять новые запросы, пока сервер дорабатывает текущие.
+func (c *HealthController) BeginShutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// LivenessHandler сообщает, что процесс жив и обрабатывает запросы.
+//
+//	@Summary		Проверка живости
+//	@Description	Всегда отвечает 200, пока процесс обрабатывает запросы. Зависимости не проверяются.
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	HealthStatus
+//	@Router			/healthz [get]
+func (c *HealthController) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, HealthStatus{Status: healthStatusOK})
+}
+
+// ReadinessHandler проверяет зависимости сервиса: базу данных, версию схемы
+// и, если включено, внешний API.
+//
+//	@Summary		Проверка готовности
+//	@Description	Проверяет соединение с базой данных, версию схемы и, если включено, внешний API.
+//	@Description	Отвечает 503, если хотя бы одна проверка не прошла или сервер начал остановку.
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	HealthStatus
+//	@Failure		503	{object}	HealthStatus	"Сервис не готов"
+//	@Router			/readyz [get]
+func (c *HealthController) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	if c.shuttingDown.Load() {
+		writeHealth(w, http.StatusServiceUnavailable, HealthStatus{
+			Status: healthStatusFail,
+			Checks: map[string]HealthCheck{"shutdown": {Status: healthStatusFail, Error: "сервер останавливается"}},
+		})
+		return
+	}
+
+	report := HealthStatus{Status: healthStatusOK, Checks: make(map[string]HealthCheck, len(c.checks))}
+	for _, check := range c.checks {
+		result := runCheck(r.Context(), check)
+		if result.Status != healthStatusOK {
+			report.Status = healthStatusFail
+		}
+		report.Checks[check.Name] = result
+	}
+
+	status := http.StatusOK
+	if report.Status != healthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealth(w, status, report)
+}
+
+// VersionHandler возвращает сведения о сборке.
+//
+//	@Summary		Сведения о сборке
+//	@Description	Коммит, время сборки, версия Go и версия схемы базы данных, которую ожидает сборка.
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	buildinfo.Info
+//	@Router			/version [get]
+func (c *HealthController) VersionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.build)
+}
+
+// runCheck выполняет проверку с ограничением времени.
+func runCheck(ctx context.Context, check ReadinessCheck) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := HealthCheck{Status: healthStatusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = healthStatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func writeHealth(w http.ResponseWriter, status int, report HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
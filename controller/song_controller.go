@@ -3,10 +3,14 @@ package controller
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"song-library/domain"
+	"song-library/logging"
+	"song-library/problem"
 	"song-library/service"
 )
 
@@ -23,54 +27,83 @@ func NewSongController(service *service.SongService) *SongController {
 // GetLibraryHandler получает список песен с фильтрацией и пагинацией.
 //
 //	@Summary		Получить библиотеку песен
-//	@Description	Получение списка песен с фильтрацией по группе, названию и дате релиза.
+//	@Description	Получение списка песен с фильтрацией по группе, названию и дате релиза в порядке ID.
+//	@Description	Параметр updated_since оставляет песни, измененные позже указанного времени, для инкрементальной синхронизации.
 //	@Tags			Songs
 //	@Param			page			query		int		false	"Номер страницы"					default(1)
 //	@Param			limit			query		int		false	"Количество элементов на странице"	default(10)
 //	@Param			group			query		string	false	"Фильтр по группе"
 //	@Param			song			query		string	false	"Фильтр по названию песни"
 //	@Param			release_date	query		string	false	"Фильтр по дате релиза"
+//	@Param			updated_since	query		string	false	"Только песни, измененные позже этого времени (RFC 3339)"
 //	@Success		200				{array}		domain.Song
-//	@Failure		500				{string}	string	"Ошибка получения библиотеки"
+//	@Failure		400				{object}	problem.Problem	"Некорректный фильтр"
+//	@Failure		500				{object}	problem.Problem	"Ошибка получения библиотеки"
+//	@Failure		504				{object}	problem.Problem	"Истекло время выполнения запроса"
 //	@Router			/library [get]
 func (c *SongController) GetLibraryHandler(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 
-	page, err := strconv.Atoi(r.PathValue("page"))
+	page, err := strconv.Atoi(query.Get("page"))
 	if err != nil || page < 1 {
 		page = 1
 	}
-	limit, err := strconv.Atoi(r.PathValue("limit"))
+	limit, err := strconv.Atoi(query.Get("limit"))
 	if err != nil || limit < 1 {
 		limit = 10
 	}
 
-	group := query.Get("group")
-	songName := query.Get("song")
-	releaseDate := query.Get("release_date")
+	filter, err := songFilterFromQuery(query)
+	if err != nil {
+		problem.Write(w, r, "op.invalid_filter", err)
+		return
+	}
 
-	songs, err := c.service.GetLibrary(page, limit)
+	songs, err := c.service.GetLibrary(r.Context(), filter, page, limit)
 	if err != nil {
-		http.Error(w, "Ошибка получения библиотеки: "+err.Error(), http.StatusInternalServerError)
+		problem.Write(w, r, "op.get_library", err)
 		return
 	}
+	if songs == nil {
+		songs = []domain.Song{}
+	}
 
-	filtered := []domain.Song{}
-	for _, song := range songs {
-		if group != "" && !strings.Contains(song.Group, group) {
-			continue
-		}
-		if songName != "" && !strings.Contains(song.Song, songName) {
-			continue
-		}
-		if releaseDate != "" && song.ReleaseDate != releaseDate {
-			continue
-		}
-		filtered = append(filtered, song)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(songs)
+}
+
+// ChangesHandler возвращает изменения песен после токена синхронизации.
+//
+//	@Summary		Получить изменения библиотеки
+//	@Description	Добавленные, измененные и удаленные песни в порядке фиксации изменений для инкрементальной синхронизации.
+//	@Description	Запрос без since возвращает только токен текущего состояния: клиент загружает библиотеку через /library или /export и дальше запрашивает изменения с этим токеном.
+//	@Description	Для удаленной песни возвращается только ее ID. Если has_more равно true, следующую страницу нужно запросить сразу с токеном из next.
+//	@Description	Изменения хранятся ограниченное время (CHANGES_RETENTION); для более старого токена возвращается 410, и библиотеку нужно загрузить заново.
+//	@Tags			Songs
+//	@Param			since	query		string	false	"Токен из поля next предыдущего ответа"
+//	@Param			limit	query		int		false	"Наибольшее количество изменений в ответе (до 1000)"	default(100)
+//	@Success		200		{object}	domain.ChangeFeed
+//	@Failure		400		{object}	problem.Problem	"Некорректный токен"
+//	@Failure		410		{object}	problem.Problem	"Токен синхронизации устарел"
+//	@Failure		500		{object}	problem.Problem	"Ошибка получения изменений"
+//	@Failure		504		{object}	problem.Problem	"Истекло время выполнения запроса"
+//	@Router			/changes [get]
+func (c *SongController) ChangesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 100
+	}
+
+	feed, err := c.service.GetChanges(r.Context(), query.Get("since"), limit)
+	if err != nil {
+		problem.Write(w, r, "op.get_changes", err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(filtered)
+	json.NewEncoder(w).Encode(feed)
 }
 
 // GetSongTextHandler получает текст песни по ID.
@@ -81,14 +114,14 @@ func (c *SongController) GetLibraryHandler(w http.ResponseWriter, r *http.Reques
 //	@Param			id		path		int	true	"ID песни"
 //	@Param			page	query		int	false	"Номер страницы (по куплетам)"	default(1)
 //	@Success		200		{object}	map[string]interface{}
-//	@Failure		400		{string}	string	"Неверный ID песни"
-//	@Failure		404		{string}	string	"Песня или куплеты не найдены"
+//	@Failure		400		{object}	problem.Problem	"Неверный ID песни"
+//	@Failure		404		{object}	problem.Problem	"Песня или куплеты не найдены"
+//	@Failure		500		{object}	problem.Problem	"Ошибка получения песни"
 //	@Router			/song/{id}/text [get]
 func (c *SongController) GetSongTextHandler(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id") // Динамический сегмент {id}
-	songID, err := strconv.Atoi(id)
-	if err != nil || songID < 1 {
-		http.Error(w, "Неверный ID песни", http.StatusBadRequest)
+	songID, err := songIDFromPath(r)
+	if err != nil {
+		problem.Write(w, r, "op.invalid_song_id", err)
 		return
 	}
 
@@ -98,16 +131,16 @@ func (c *SongController) GetSongTextHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	limit := 1
-	song, err := c.service.GetSongByID(songID)
+	song, err := c.service.GetSongByID(r.Context(), songID)
 	if err != nil {
-		http.Error(w, "Ошибка получения песни: "+err.Error(), http.StatusNotFound)
+		problem.Write(w, r, "op.get_song", err)
 		return
 	}
 
 	verses := strings.Split(song.Text, "\n\n")
 	start := (page - 1) * limit
 	if start >= len(verses) {
-		http.Error(w, "Куплеты не найдены", http.StatusNotFound)
+		problem.Write(w, r, "op.get_song_text", domain.NewNotFoundError(domain.CodeVersesNotFound, "куплеты не найдены"))
 		return
 	}
 
@@ -117,10 +150,12 @@ func (c *SongController) GetSongTextHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	response := map[string]interface{}{
-		"id":     song.ID,
-		"group":  song.Group,
-		"song":   song.Song,
-		"verses": verses[start:end],
+		"id":         song.ID,
+		"group":      song.Group,
+		"song":       song.Song,
+		"verses":     verses[start:end],
+		"created_at": song.CreatedAt,
+		"updated_at": song.UpdatedAt,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -134,19 +169,19 @@ func (c *SongController) GetSongTextHandler(w http.ResponseWriter, r *http.Reque
 //	@Tags			Songs
 //	@Param			id	path	int	true	"ID песни"
 //	@Success		204	"Песня удалена"
-//	@Failure		400	{string}	string	"Неверный ID песни"
-//	@Failure		500	{string}	string	"Ошибка удаления песни"
+//	@Failure		400	{object}	problem.Problem	"Неверный ID песни"
+//	@Failure		404	{object}	problem.Problem	"Песня не найдена"
+//	@Failure		500	{object}	problem.Problem	"Ошибка удаления песни"
 //	@Router			/song/{id} [delete]
 func (c *SongController) DeleteSongHandler(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
-	songID, err := strconv.Atoi(id)
-	if err != nil || songID < 1 {
-		http.Error(w, "Неверный ID песни", http.StatusBadRequest)
+	songID, err := songIDFromPath(r)
+	if err != nil {
+		problem.Write(w, r, "op.invalid_song_id", err)
 		return
 	}
 
-	if err := c.service.DeleteSong(songID); err != nil {
-		http.Error(w, "Ошибка удаления песни: "+err.Error(), http.StatusInternalServerError)
+	if err := c.service.DeleteSong(r.Context(), songID); err != nil {
+		problem.Write(w, r, "op.delete_song", err)
 		return
 	}
 
@@ -156,31 +191,32 @@ func (c *SongController) DeleteSongHandler(w http.ResponseWriter, r *http.Reques
 // UpdateSongHandler обновляет данные песни по ID.
 //
 //	@Summary		Обновить данные песни
-//	@Description	Обновление информации о песне по ID.
+//	@Description	Замена всех данных песни по ID. Неизвестные поля в теле запроса отклоняются.
+//	@Description	Пара группа/название уникальна: если она уже занята другой песней, возвращается 409 с кодом song_exists.
 //	@Tags			Songs
-//	@Param			id		path	int			true	"ID песни"
-//	@Param			song	body	domain.Song	true	"Данные песни"
+//	@Param			id		path	int							true	"ID песни"
+//	@Param			song	body	domain.SongUpdateRequest	true	"Данные песни"
 //	@Success		200		"Песня обновлена"
-//	@Failure		400		{string}	string	"Ошибка декодирования данных или неверный ID"
-//	@Failure		500		{string}	string	"Ошибка обновления песни"
+//	@Failure		400		{object}	problem.Problem	"Ошибка декодирования данных или неверный ID"
+//	@Failure		404		{object}	problem.Problem	"Песня не найдена"
+//	@Failure		409		{object}	problem.Problem	"Песня с такой группой и названием уже существует"
+//	@Failure		500		{object}	problem.Problem	"Ошибка обновления песни"
 //	@Router			/song/{id} [put]
 func (c *SongController) UpdateSongHandler(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
-	songID, err := strconv.Atoi(id)
-	if err != nil || songID < 1 {
-		http.Error(w, "Неверный ID песни", http.StatusBadRequest)
+	songID, err := songIDFromPath(r)
+	if err != nil {
+		problem.Write(w, r, "op.invalid_song_id", err)
 		return
 	}
 
-	var song domain.Song
-	if err := json.NewDecoder(r.Body).Decode(&song); err != nil {
-		http.Error(w, "Ошибка декодирования данных песни: "+err.Error(), http.StatusBadRequest)
+	var request domain.SongUpdateRequest
+	if err := decodeJSON(r, &request); err != nil {
+		problem.Write(w, r, "op.decode_song", err)
 		return
 	}
-	song.ID = songID
 
-	if err := c.service.UpdateSong(song); err != nil {
-		http.Error(w, "Ошибка обновления песни: "+err.Error(), http.StatusInternalServerError)
+	if err := c.service.UpdateSong(r.Context(), songID, request); err != nil {
+		problem.Write(w, r, "op.update_song", err)
 		return
 	}
 
@@ -190,37 +226,128 @@ func (c *SongController) UpdateSongHandler(w http.ResponseWriter, r *http.Reques
 // AddSongHandler добавляет новую песню в библиотеку.
 //
 //	@Summary		Добавить песню
-//	@Description	Добавление новой песни в библиотеку.
+//	@Description	Добавление новой песни в библиотеку. Неизвестные поля в теле запроса отклоняются.
+//	@Description	Пара группа/название уникальна: повторное добавление той же песни возвращает 409 с кодом song_exists.
 //	@Tags			Songs
 //	@Param			song	body	domain.SongCreateRequest	true	"Данные для создания песни"
 //	@Success		201		"Песня добавлена"
-//	@Failure		400		{string}	string	"Ошибка декодирования данных песни"
-//	@Failure		500		{string}	string	"Ошибка добавления песни"
+//	@Failure		400		{object}	problem.Problem	"Ошибка декодирования данных песни или пустые поля"
+//	@Failure		404		{object}	problem.Problem	"Песня не найдена во внешнем API"
+//	@Failure		409		{object}	problem.Problem	"Песня с такой группой и названием уже существует"
+//	@Failure		500		{object}	problem.Problem	"Ошибка добавления песни"
+//	@Failure		502		{object}	problem.Problem	"Внешний API недоступен"
 //	@Router			/song [post]
 func (c *SongController) AddSongHandler(w http.ResponseWriter, r *http.Request) {
 	var request domain.SongCreateRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Ошибка декодирования данных песни: "+err.Error(), http.StatusBadRequest)
+	if err := decodeJSON(r, &request); err != nil {
+		problem.Write(w, r, "op.decode_song", err)
 		return
 	}
 
-	// Проверка обязательных полей
-	if request.Group == "" || request.Song == "" {
-		http.Error(w, "Поля 'group' и 'song' обязательны", http.StatusBadRequest)
+	// Добавление песни через сервис
+	if err := c.service.AddSong(r.Context(), request); err != nil {
+		problem.Write(w, r, "op.add_song", err)
 		return
 	}
 
-	// Преобразование данных в доменную модель
-	newSong := domain.Song{
-		Group: request.Group,
-		Song:  request.Song,
+	w.WriteHeader(http.StatusCreated)
+}
+
+// EnrichSongHandler дополняет песню деталями из внешнего API.
+//
+//	@Summary		Получить детали песни
+//	@Description	Заполняет пустые дату релиза, текст и ссылку песни данными внешнего API. Заполненные поля не меняются.
+//	@Description	Если детали изменились, подписчики вебхуков получают событие song.enriched.
+//	@Tags			Songs
+//	@Param			id	path		int	true	"ID песни"
+//	@Success		200	{object}	domain.Song
+//	@Failure		400	{object}	problem.Problem	"Неверный ID песни"
+//	@Failure		404	{object}	problem.Problem	"Песня не найдена в библиотеке или во внешнем API"
+//	@Failure		500	{object}	problem.Problem	"Ошибка сохранения деталей"
+//	@Failure		502	{object}	problem.Problem	"Внешний API недоступен"
+//	@Router			/song/{id}/enrich [post]
+func (c *SongController) EnrichSongHandler(w http.ResponseWriter, r *http.Request) {
+	songID, err := songIDFromPath(r)
+	if err != nil {
+		problem.Write(w, r, "op.invalid_song_id", err)
+		return
 	}
 
-	// Добавление песни через сервис
-	if err := c.service.AddSong(newSong); err != nil {
-		http.Error(w, "Ошибка добавления песни: "+err.Error(), http.StatusInternalServerError)
+	song, err := c.service.EnrichSong(r.Context(), songID)
+	if err != nil {
+		problem.Write(w, r, "op.enrich_song", err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(song)
+}
+
+// decodeJSON читает из тела запроса ровно один JSON-объект без неизвестных полей.
+func decodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return domain.NewMalformedBodyError(err)
+	}
+	if decoder.More() {
+		return &domain.ValidationError{Code: domain.CodeMalformedBody, Fields: []domain.FieldError{domain.NewFieldError("body", "trailing_data")}}
+	}
+	return nil
+}
+
+// songIDFromPath читает ID песни из сегмента пути {id}.
+func songIDFromPath(r *http.Request) (int, error) {
+	songID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || songID < 1 {
+		return 0, domain.NewValidationError("id", "invalid_id", r.PathValue("id"))
+	}
+	logging.SetSongID(r.Context(), songID)
+	return songID, nil
+}
+
+// songFilterFromQuery читает параметры фильтрации песен из строки запроса.
+func songFilterFromQuery(query url.Values) (domain.SongFilter, error) {
+	filter := domain.SongFilter{
+		Group:       query.Get("group"),
+		Song:        query.Get("song"),
+		ReleaseDate: query.Get("release_date"),
+	}
+	if since := query.Get("updated_since"); since != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			return domain.SongFilter{}, domain.NewValidationError("updated_since", "timestamp")
+		}
+		filter.UpdatedSince = parsed
+	}
+	return filter, nil
+}
+
+// BatchHandler выполняет пакет операций над песнями в одной транзакции.
+//
+//	@Summary		Пакетное изменение песен
+//	@Description	Выполнение списка операций create, update и delete в одной транзакции.
+//	@Description	В режиме all_or_nothing любая ошибка откатывает весь пакет, в режиме best_effort откатываются только ошибочные операции.
+//	@Tags			Songs
+//	@Param			batch	body		domain.BatchRequest	true	"Пакет операций"
+//	@Success		200		{object}	domain.BatchResponse
+//	@Failure		400		{object}	problem.Problem	"Ошибка декодирования данных или некорректный пакет"
+//	@Failure		500		{object}	problem.Problem	"Ошибка выполнения пакета"
+//	@Failure		504		{object}	problem.Problem	"Истекло время выполнения пакета"
+//	@Router			/song/batch [post]
+func (c *SongController) BatchHandler(w http.ResponseWriter, r *http.Request) {
+	var request domain.BatchRequest
+	if err := decodeJSON(r, &request); err != nil {
+		problem.Write(w, r, "op.decode_batch", err)
+		return
+	}
+
+	response, err := c.service.ExecuteBatch(r.Context(), request)
+	if err != nil {
+		problem.Write(w, r, "op.execute_batch", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
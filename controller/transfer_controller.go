@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"song-library/domain"
+	"song-library/problem"
+	"song-library/service"
+	"song-library/songio"
+)
+
+// TransferController представляет контроллер для массового импорта и экспорта песен.
+type TransferController struct {
+	service *service.SongService
+}
+
+// NewTransferController создает новый TransferController.
+func NewTransferController(service *service.SongService) *TransferController {
+	return &TransferController{service: service}
+}
+
+// ImportProblem описывает ошибку импорта вместе с результатами строк,
+// обработанных до нее.
+type ImportProblem struct {
+	problem.Problem
+	Report *domain.ImportReport `json:"report,omitempty"` // Результаты строк до ошибки
+}
+
+// ImportHandler импортирует песни из CSV или NDJSON.
+//
+//	@Summary		Импортировать песни
+//	@Description	Потоковый импорт песен из CSV (с заголовком group,song[,release_date,text,link]) или NDJSON.
+//	@Description	Формат определяется параметром format или заголовком Content-Type.
+//	@Description	Строки сохраняются пакетами по batch_size в отдельных транзакциях. Если файл не удалось дочитать, уже сохраненные пакеты остаются в библиотеке,
+//	@Description	а ответ об ошибке содержит в поле report результаты строк, обработанных до нее; строки незаполненного пакета не сохраняются и попадают в отчет со статусом failed.
+//	@Tags			Import
+//	@Accept			text/csv
+//	@Accept			application/x-ndjson
+//	@Param			format		query		string	false	"Формат файла"						Enums(csv, ndjson)
+//	@Param			dry_run		query		bool	false	"Проверить файл без сохранения"
+//	@Param			batch_size	query		int		false	"Количество строк в одной транзакции"	default(500)
+//	@Param			enrich		query		bool	false	"Запрашивать недостающие детали во внешнем API"
+//	@Success		200			{object}	domain.ImportReport
+//	@Failure		400			{object}	ImportProblem	"Неизвестный формат или некорректный файл"
+//	@Failure		500			{object}	ImportProblem	"Ошибка сохранения песен"
+//	@Router			/import [post]
+func (c *TransferController) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var format songio.Format
+	if name := query.Get("format"); name != "" {
+		parsed, err := songio.ParseFormat(name)
+		if err != nil {
+			problem.Write(w, r, "op.invalid_format", unsupportedFormat("unsupported_format", name))
+			return
+		}
+		format = parsed
+	} else if detected, ok := songio.FormatFromContentType(r.Header.Get("Content-Type")); ok {
+		format = detected
+	} else {
+		problem.Write(w, r, "op.invalid_format", unsupportedFormat("format_required"))
+		return
+	}
+
+	batchSize, err := strconv.Atoi(query.Get("batch_size"))
+	if err != nil || batchSize < 1 {
+		batchSize = 0
+	}
+	options := domain.ImportOptions{
+		DryRun:    query.Get("dry_run") == "true",
+		BatchSize: batchSize,
+		Enrich:    query.Get("enrich") == "true",
+	}
+
+	reader, err := songio.NewReader(r.Body, format)
+	if err != nil {
+		problem.Write(w, r, "op.read_import", domain.NewMalformedBodyError(err))
+		return
+	}
+
+	report, err := c.service.ImportSongs(r.Context(), reader, options)
+	if err != nil {
+		response := ImportProblem{Problem: problem.New(r, "op.import_songs", err), Report: report}
+		problem.SetHeaders(w.Header())
+		w.WriteHeader(response.Status)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ExportHandler выгружает библиотеку песен потоком.
+//
+//	@Summary		Экспортировать библиотеку
+//	@Description	Потоковая выгрузка песен в порядке ID в формате NDJSON, CSV или TSV.
+//	@Description	Принимает те же фильтры, что и /library.
+//	@Tags			Import
+//	@Produce		application/x-ndjson
+//	@Produce		text/csv
+//	@Produce		text/tab-separated-values
+//	@Param			format			query		string	false	"Формат выгрузки"	Enums(ndjson, csv, tsv)	default(ndjson)
+//	@Param			group			query		string	false	"Фильтр по группе"
+//	@Param			song			query		string	false	"Фильтр по названию песни"
+//	@Param			release_date	query		string	false	"Фильтр по дате релиза"
+//	@Param			updated_since	query		string	false	"Только песни, измененные позже этого времени (RFC 3339)"
+//	@Success		200				{file}		file
+//	@Failure		400				{object}	problem.Problem	"Неизвестный формат или некорректный фильтр"
+//	@Failure		500				{object}	problem.Problem	"Ошибка экспорта песен"
+//	@Router			/export [get]
+func (c *TransferController) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter, err := songFilterFromQuery(query)
+	if err != nil {
+		problem.Write(w, r, "op.invalid_filter", err)
+		return
+	}
+
+	format := songio.FormatNDJSON
+	if name := query.Get("format"); name != "" {
+		parsed, err := songio.ParseFormat(name)
+		if err != nil {
+			problem.Write(w, r, "op.invalid_format", unsupportedFormat("unsupported_format", name))
+			return
+		}
+		format = parsed
+	}
+
+	body := &bodyTracker{ResponseWriter: w}
+	writer, err := songio.NewWriter(body, format)
+	if err != nil {
+		problem.Write(w, r, "op.invalid_format", unsupportedFormat("unsupported_format", string(format)))
+		return
+	}
+
+	filename := fmt.Sprintf("songs-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if err := c.service.ExportSongs(r.Context(), filter, writer); err != nil {
+		if !body.written {
+			w.Header().Del("Content-Disposition")
+			problem.Write(w, r, "op.export_songs", err)
+			return
+		}
+		// Часть выгрузки уже отправлена, поэтому обрываем соединение,
+		// чтобы клиент не принял неполный файл за целый.
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// unsupportedFormat возвращает ошибку валидации параметра format по правилу rule.
+func unsupportedFormat(rule string, args ...any) error {
+	return &domain.ValidationError{Code: domain.CodeUnsupportedFormat, Fields: []domain.FieldError{domain.NewFieldError("format", rule, args...)}}
+}
+
+// bodyTracker запоминает, было ли что-то записано в тело ответа.
+type bodyTracker struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (t *bodyTracker) Write(p []byte) (int, error) {
+	t.written = true
+	return t.ResponseWriter.Write(p)
+}
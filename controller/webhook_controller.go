@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"song-library/domain"
+	"song-library/problem"
+	"song-library/service"
+)
+
+// WebhookController управляет подписками на события песен и их доставками.
+type WebhookController struct {
+	service *service.WebhookService
+}
+
+// NewWebhookController создает новый WebhookController.
+func NewWebhookController(service *service.WebhookService) *WebhookController {
+	return &WebhookController{service: service}
+}
+
+// CreateWebhookHandler создает подписку на события песен.
+//
+//	@Summary		Создать вебхук
+//	@Description	Подписка на события song.created, song.updated, song.deleted и song.enriched.
+//	@Description	События отправляются POST-запросом с JSON-телом domain.WebhookEvent и заголовками X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp и X-Webhook-Signature.
+//	@Description	Подпись — sha256=<hex>, где hex — HMAC-SHA256 с ключом secret от строки "<X-Webhook-Timestamp>.<тело запроса>".
+//	@Description	Успехом считается ответ 2xx; иначе попытки повторяются с растущей паузой. Событие может прийти повторно с тем же X-Webhook-Delivery.
+//	@Description	Если secret не задан, создается случайный ключ. Ключ возвращается только в этом ответе.
+//	@Tags			Webhooks
+//	@Param			webhook	body		domain.WebhookCreateRequest	true	"Адрес, события и ключ подписи"
+//	@Success		201		{object}	domain.Webhook
+//	@Failure		400		{object}	problem.Problem	"Ошибка декодирования или некорректные поля"
+//	@Failure		500		{object}	problem.Problem	"Ошибка создания вебхука"
+//	@Router			/webhooks [post]
+func (c *WebhookController) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var request domain.WebhookCreateRequest
+	if err := decodeJSON(r, &request); err != nil {
+		problem.Write(w, r, "op.decode_webhook", err)
+		return
+	}
+
+	webhook, err := c.service.CreateWebhook(r.Context(), request)
+	if err != nil {
+		problem.Write(w, r, "op.create_webhook", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// GetWebhooksHandler возвращает все подписки.
+//
+//	@Summary		Список вебхуков
+//	@Description	Все подписки в порядке ID, без ключей подписи.
+//	@Tags			Webhooks
+//	@Success		200	{array}		domain.Webhook
+//	@Failure		500	{object}	problem.Problem	"Ошибка получения вебхуков"
+//	@Router			/webhooks [get]
+func (c *WebhookController) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := c.service.GetWebhooks(r.Context())
+	if err != nil {
+		problem.Write(w, r, "op.get_webhooks", err)
+		return
+	}
+	if webhooks == nil {
+		webhooks = []domain.Webhook{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// GetWebhookHandler возвращает подписку по ID.
+//
+//	@Summary		Получить вебхук
+//	@Tags			Webhooks
+//	@Param			id	path		int	true	"ID вебхука"
+//	@Success		200	{object}	domain.Webhook
+//	@Failure		400	{object}	problem.Problem	"Неверный ID вебхука"
+//	@Failure		404	{object}	problem.Problem	"Вебхук не найден"
+//	@Router			/webhooks/{id} [get]
+func (c *WebhookController) GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIDFromPath(r)
+	if err != nil {
+		problem.Write(w, r, "op.invalid_webhook_id", err)
+		return
+	}
+
+	webhook, err := c.service.GetWebhook(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, "op.get_webhook", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// DeleteWebhookHandler удаляет подписку.
+//
+//	@Summary		Удалить вебхук
+//	@Description	Удаляет подписку вместе с очередью и журналом ее доставок.
+//	@Tags			Webhooks
+//	@Param			id	path	int	true	"ID вебхука"
+//	@Success		204	"Вебхук удален"
+//	@Failure		400	{object}	problem.Problem	"Неверный ID вебхука"
+//	@Failure		404	{object}	problem.Problem	"Вебхук не найден"
+//	@Router			/webhooks/{id} [delete]
+func (c *WebhookController) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIDFromPath(r)
+	if err != nil {
+		problem.Write(w, r, "op.invalid_webhook_id", err)
+		return
+	}
+
+	if err := c.service.DeleteWebhook(r.Context(), id); err != nil {
+		problem.Write(w, r, "op.delete_webhook", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveriesHandler возвращает журнал доставок подписки.
+//
+//	@Summary		Журнал доставок вебхука
+//	@Description	Доставки от новых к старым с состоянием, числом попыток и результатом последней попытки.
+//	@Description	Следующая страница запрашивается с before, равным ID последней доставки в ответе.
+//	@Tags			Webhooks
+//	@Param			id		path		int		true	"ID вебхука"
+//	@Param			status	query		string	false	"Состояние: pending, delivered или failed"
+//	@Param			before	query		int		false	"Только доставки с ID меньше указанного"
+//	@Param			limit	query		int		false	"Количество доставок (до 500)"	default(50)
+//	@Success		200		{array}		domain.WebhookDelivery
+//	@Failure		400		{object}	problem.Problem	"Неверный ID вебхука или параметры"
+//	@Failure		404		{object}	problem.Problem	"Вебхук не найден"
+//	@Router			/webhooks/{id}/deliveries [get]
+func (c *WebhookController) GetDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIDFromPath(r)
+	if err != nil {
+		problem.Write(w, r, "op.invalid_webhook_id", err)
+		return
+	}
+
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	before, err := strconv.ParseInt(query.Get("before"), 10, 64)
+	if err != nil || before < 0 {
+		before = 0
+	}
+
+	deliveries, err := c.service.GetDeliveries(r.Context(), id, query.Get("status"), before, limit)
+	if err != nil {
+		problem.Write(w, r, "op.get_deliveries", err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []domain.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// RedeliverHandler ставит доставку в очередь заново.
+//
+//	@Summary		Повторить доставку
+//	@Description	Отправляет событие заново с новым циклом попыток, в том числе уже доставленное или неудачное.
+//	@Tags			Webhooks
+//	@Param			id			path		int	true	"ID вебхука"
+//	@Param			delivery_id	path		int	true	"ID доставки"
+//	@Success		202			{object}	domain.WebhookDelivery
+//	@Failure		400			{object}	problem.Problem	"Неверный ID вебхука или доставки"
+//	@Failure		404			{object}	problem.Problem	"Доставка не найдена"
+//	@Router			/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (c *WebhookController) RedeliverHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIDFromPath(r)
+	if err != nil {
+		problem.Write(w, r, "op.invalid_webhook_id", err)
+		return
+	}
+	deliveryID, err := strconv.ParseInt(r.PathValue("delivery_id"), 10, 64)
+	if err != nil || deliveryID < 1 {
+		problem.Write(w, r, "op.redeliver", domain.NewValidationError("delivery_id", "invalid_delivery_id", r.PathValue("delivery_id")))
+		return
+	}
+
+	delivery, err := c.service.Redeliver(r.Context(), id, deliveryID)
+	if err != nil {
+		problem.Write(w, r, "op.redeliver", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// webhookIDFromPath читает ID вебхука из сегмента пути {id}.
+func webhookIDFromPath(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		return 0, domain.NewValidationError("id", "invalid_webhook_id", r.PathValue("id"))
+	}
+	return id, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"song-library/config"
+)
+
+// isMemoryURL сообщает, что DB_URL выбирает хранилище в памяти.
+func isMemoryURL(dbURL string) bool {
+	return strings.HasPrefix(dbURL, "memory:")
+}
+
+// isSQLiteURL сообщает, что DB_URL выбирает файл SQLite.
+func isSQLiteURL(dbURL string) bool {
+	return strings.HasPrefix(dbURL, "sqlite://")
+}
+
+// isPostgresURL сообщает, что DB_URL указывает на PostgreSQL.
+func isPostgresURL(dbURL string) bool {
+	return !isMemoryURL(dbURL) && !isSQLiteURL(dbURL)
+}
+
+// databaseName возвращает имя СУБД для метки метрик пула соединений.
+func databaseName(dbURL string) string {
+	if isSQLiteURL(dbURL) {
+		return "sqlite"
+	}
+	return "postgres"
+}
+
+// openDatabase открывает пул соединений с базой данных, настраивает его и
+// ждет, пока база станет доступна: пока она, например, еще запускается,
+// проверка соединения повторяется с растущей паузой в пределах
+// DB_CONNECT_TIMEOUT. ctx прерывает ожидание.
+func openDatabase(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*sql.DB, error) {
+	driver, dsn := "postgres", cfg.DBURL
+	if isSQLiteURL(cfg.DBURL) {
+		driver, dsn = "sqlite", sqliteDSN(cfg.DBURL)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к базе данных: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+
+	if err := waitForDatabase(ctx, db, cfg.DBConnectTimeout, logger); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Паузы между попытками подключиться к базе данных при запуске.
+const (
+	connectRetryMin = 250 * time.Millisecond
+	connectRetryMax = 5 * time.Second
+)
+
+// waitForDatabase проверяет соединение с базой данных, пока оно не
+// установится, не истечет timeout или не будет отменен ctx. Пауза между
+// попытками удваивается от connectRetryMin до connectRetryMax.
+func waitForDatabase(ctx context.Context, db *sql.DB, timeout time.Duration, logger *slog.Logger) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	delay := connectRetryMin
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(waitCtx)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("соединение с базой данных установлено", "attempts", attempt)
+			}
+			return nil
+		}
+		// Ошибку прерванной по сроку попытки не показываем, если есть настоящая
+		if waitCtx.Err() == nil || lastErr == nil {
+			lastErr = err
+		}
+
+		if waitCtx.Err() == nil {
+			logger.Warn("база данных недоступна, повтор подключения", "attempt", attempt, "retry_in", delay, "error", err)
+			select {
+			case <-time.After(delay):
+				delay = min(2*delay, connectRetryMax)
+				continue
+			case <-waitCtx.Done():
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("ожидание базы данных прервано после %d попыток: %w", attempt, err)
+		}
+		return fmt.Errorf("база данных недоступна после %d попыток за %s: %w", attempt, timeout, lastErr)
+	}
+}
+
+// sqliteDSN превращает sqlite://путь?параметры в строку подключения драйвера.
+// Параметры x-* предназначены для migrate и отбрасываются. Если ожидание
+// блокировки не задано, запись ждет занятую базу до 5 секунд.
+func sqliteDSN(dbURL string) string {
+	path, rawQuery, _ := strings.Cut(strings.TrimPrefix(dbURL, "sqlite://"), "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		log.Fatalf("Некорректные параметры SQLite в DB_URL: %v", err)
+	}
+	for key := range query {
+		if strings.HasPrefix(key, "x-") {
+			query.Del(key)
+		}
+	}
+	if !strings.Contains(strings.Join(query["_pragma"], ","), "busy_timeout") {
+		query.Add("_pragma", "busy_timeout(5000)")
+	}
+	// Параметры-время передаются в формате, который понимают функции даты SQLite
+	if !query.Has("_time_format") {
+		query.Set("_time_format", "sqlite")
+	}
+	return path + "?" + query.Encode()
+}
@@ -16,287 +16,1423 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/info": {
+        "/changes": {
             "get": {
-                "description": "Получение деталей о песне по имени группы и названию песни.",
+                "description": "Добавленные, измененные и удаленные песни в порядке фиксации изменений для инкрементальной синхронизации.\nЗапрос без since возвращает только токен текущего состояния: клиент загружает библиотеку через /library или /export и дальше запрашивает изменения с этим токеном.\nДля удаленной песни возвращается только ее ID. Если has_more равно true, следующую страницу нужно запросить сразу с токеном из next.\nИзменения хранятся ограниченное время (CHANGES_RETENTION); для более старого токена возвращается 410, и библиотеку нужно загрузить заново.",
                 "tags": [
-                    "Info"
+                    "Songs"
                 ],
-                "summary": "Получить информацию о песне",
+                "summary": "Получить изменения библиотеки",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Название группы",
-                        "name": "group",
-                        "in": "query",
-                        "required": true
+                        "description": "Токен из поля next предыдущего ответа",
+                        "name": "since",
+                        "in": "query"
                     },
                     {
-                        "type": "string",
-                        "description": "Название песни",
-                        "name": "song",
-                        "in": "query",
-                        "required": true
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Наибольшее количество изменений в ответе (до 1000)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.SongDetail"
+                            "$ref": "#/definitions/domain.ChangeFeed"
                         }
                     },
                     "400": {
-                        "description": "Параметры обязательны",
+                        "description": "Некорректный токен",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "Токен синхронизации устарел",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
-                        "description": "Ошибка получения данных",
+                        "description": "Ошибка получения изменений",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Истекло время выполнения запроса",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/library": {
+        "/errors": {
             "get": {
-                "description": "Получение списка песен с фильтрацией по группе, названию и дате релиза.",
+                "description": "Все коды, которые могут прийти в поле code ответа application/problem+json, с HTTP-статусами.\nПоле type ответа об ошибке ссылается на описание кода: /errors/{code}.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Songs"
+                    "Errors"
                 ],
-                "summary": "Получить библиотеку песен",
+                "summary": "Каталог кодов ошибок",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/problem.CodeInfo"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/errors/{code}": {
+            "get": {
+                "description": "Описание кода ошибки, на которое ссылается поле type ответа application/problem+json.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Errors"
+                ],
+                "summary": "Описание кода ошибки",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Номер страницы",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Количество элементов на странице",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Фильтр по группе",
-                        "name": "group",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Фильтр по названию песни",
-                        "name": "song",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
-                        "description": "Фильтр по дате релиза",
-                        "name": "release_date",
-                        "in": "query"
+                        "description": "Код ошибки",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Song"
-                            }
+                            "$ref": "#/definitions/problem.CodeInfo"
                         }
                     },
-                    "500": {
-                        "description": "Ошибка получения библиотеки",
+                    "404": {
+                        "description": "Код не найден",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/song": {
-            "post": {
-                "description": "Добавление новой песни в библиотеку.",
+        "/events": {
+            "get": {
+                "description": "Server-Sent Events с изменениями песен в порядке фиксации: события song.created, song.updated и song.deleted с данными domain.SongChange.\nID события — токен синхронизации, как в поле next ответа /changes. После разрыва EventSource передает его в заголовке Last-Event-ID, и поток продолжается без пропусков.\nСобытие reset означает, что продолжить поток без пропусков нельзя (изменения удалены по CHANGES_RETENTION или база восстановлена из архива): клиент загружает библиотеку заново, а поток продолжается с ID события reset.\nПараметр group (можно несколько) оставляет только изменения песен этих групп, без учета регистра.",
+                "produces": [
+                    "text/event-stream"
+                ],
                 "tags": [
                     "Songs"
                 ],
-                "summary": "Добавить песню",
+                "summary": "Поток изменений библиотеки",
                 "parameters": [
                     {
-                        "description": "Данные для создания песни",
-                        "name": "song",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/domain.SongCreateRequest"
-                        }
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Группы песен",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен, после которого начать поток; по умолчанию — с текущего момента",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID последнего полученного события; важнее since",
+                        "name": "Last-Event-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Песня добавлена"
+                    "200": {
+                        "description": "Поток событий",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SongChange"
+                        }
                     },
                     "400": {
-                        "description": "Ошибка декодирования данных песни",
+                        "description": "Некорректный токен",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
-                        "description": "Ошибка добавления песни",
+                        "description": "Ошибка подписки на события",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/song/{id}": {
-            "put": {
-                "description": "Обновление информации о песне по ID.",
+        "/export": {
+            "get": {
+                "description": "Потоковая выгрузка песен в порядке ID в формате NDJSON, CSV или TSV.\nПринимает те же фильтры, что и /library.",
+                "produces": [
+                    "application/x-ndjson",
+                    "text/csv",
+                    "text/tab-separated-values"
+                ],
                 "tags": [
-                    "Songs"
+                    "Import"
                 ],
-                "summary": "Обновить данные песни",
+                "summary": "Экспортировать библиотеку",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID песни",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "enum": [
+                            "ndjson",
+                            "csv",
+                            "tsv"
+                        ],
+                        "type": "string",
+                        "default": "ndjson",
+                        "description": "Формат выгрузки",
+                        "name": "format",
+                        "in": "query"
                     },
                     {
-                        "description": "Данные песни",
+                        "type": "string",
+                        "description": "Фильтр по группе",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по названию песни",
                         "name": "song",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/domain.Song"
-                        }
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по дате релиза",
+                        "name": "release_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Только песни, измененные позже этого времени (RFC 3339)",
+                        "name": "updated_since",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Песня обновлена"
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
                     },
                     "400": {
-                        "description": "Ошибка декодирования данных или неверный ID",
+                        "description": "Неизвестный формат или некорректный фильтр",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
-                        "description": "Ошибка обновления песни",
+                        "description": "Ошибка экспорта песен",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Удаление песни из библиотеки по ID.",
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Всегда отвечает 200, пока процесс обрабатывает запросы. Зависимости не проверяются.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Songs"
+                    "Health"
                 ],
-                "summary": "Удалить песню",
+                "summary": "Проверка живости",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.HealthStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/import": {
+            "post": {
+                "description": "Потоковый импорт песен из CSV (с заголовком group,song[,release_date,text,link]) или NDJSON.\nФормат определяется параметром format или заголовком Content-Type.\nСтроки сохраняются пакетами по batch_size в отдельных транзакциях. Если файл не удалось дочитать, уже сохраненные пакеты остаются в библиотеке,\nа ответ об ошибке содержит в поле report результаты строк, обработанных до нее; строки незаполненного пакета не сохраняются и попадают в отчет со статусом failed.",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "Импортировать песни",
                 "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Формат файла",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Проверить файл без сохранения",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "ID песни",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": 500,
+                        "description": "Количество строк в одной транзакции",
+                        "name": "batch_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Запрашивать недостающие детали во внешнем API",
+                        "name": "enrich",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Песня удалена"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ImportReport"
+                        }
                     },
                     "400": {
-                        "description": "Неверный ID песни",
+                        "description": "Неизвестный формат или некорректный файл",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/controller.ImportProblem"
                         }
                     },
                     "500": {
-                        "description": "Ошибка удаления песни",
+                        "description": "Ошибка сохранения песен",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/controller.ImportProblem"
                         }
                     }
                 }
             }
         },
-        "/song/{id}/text": {
+        "/info": {
             "get": {
-                "description": "Получение текста песни по ID с возможностью пагинации по куплетам.",
+                "description": "Получение деталей о песне по имени группы и названию песни.",
                 "tags": [
-                    "Songs"
+                    "Info"
                 ],
-                "summary": "Получить текст песни",
+                "summary": "Получить информацию о песне",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID песни",
-                        "name": "id",
-                        "in": "path",
+                        "type": "string",
+                        "description": "Название группы",
+                        "name": "group",
+                        "in": "query",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Номер страницы (по куплетам)",
-                        "name": "page",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Название песни",
+                        "name": "song",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/domain.SongDetail"
                         }
                     },
                     "400": {
-                        "description": "Неверный ID песни",
+                        "description": "Параметры обязательны",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "404": {
-                        "description": "Песня или куплеты не найдены",
+                    "500": {
+                        "description": "Ошибка получения данных",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "domain.Song": {
-            "type": "object",
-            "properties": {
-                "group": {
-                    "description": "Название группы",
+        },
+        "/info/cache": {
+            "get": {
+                "description": "Получение счетчиков попаданий и промахов кэша ответов внешнего API.",
+                "tags": [
+                    "Info"
+                ],
+                "summary": "Статистика кэша деталей песен",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.CacheStats"
+                        }
+                    }
+                }
+            }
+        },
+        "/library": {
+            "get": {
+                "description": "Получение списка песен с фильтрацией по группе, названию и дате релиза в порядке ID.\nПараметр updated_since оставляет песни, измененные позже указанного времени, для инкрементальной синхронизации.",
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Получить библиотеку песен",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Количество элементов на странице",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по группе",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по названию песни",
+                        "name": "song",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по дате релиза",
+                        "name": "release_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Только песни, измененные позже этого времени (RFC 3339)",
+                        "name": "updated_since",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Song"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный фильтр",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка получения библиотеки",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Истекло время выполнения запроса",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет соединение с базой данных, версию схемы и, если включено, внешний API.\nОтвечает 503, если хотя бы одна проверка не прошла или сервер начал остановку.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Проверка готовности",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.HealthStatus"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис не готов",
+                        "schema": {
+                            "$ref": "#/definitions/controller.HealthStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/song": {
+            "post": {
+                "description": "Добавление новой песни в библиотеку. Неизвестные поля в теле запроса отклоняются.\nПара группа/название уникальна: повторное добавление той же песни возвращает 409 с кодом song_exists.",
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Добавить песню",
+                "parameters": [
+                    {
+                        "description": "Данные для создания песни",
+                        "name": "song",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.SongCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Песня добавлена"
+                    },
+                    "400": {
+                        "description": "Ошибка декодирования данных песни или пустые поля",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена во внешнем API",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Песня с такой группой и названием уже существует",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка добавления песни",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Внешний API недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/song/batch": {
+            "post": {
+                "description": "Выполнение списка операций create, update и delete в одной транзакции.\nВ режиме all_or_nothing любая ошибка откатывает весь пакет, в режиме best_effort откатываются только ошибочные операции.",
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Пакетное изменение песен",
+                "parameters": [
+                    {
+                        "description": "Пакет операций",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.BatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка декодирования данных или некорректный пакет",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка выполнения пакета",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Истекло время выполнения пакета",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/song/{id}": {
+            "put": {
+                "description": "Замена всех данных песни по ID. Неизвестные поля в теле запроса отклоняются.\nПара группа/название уникальна: если она уже занята другой песней, возвращается 409 с кодом song_exists.",
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Обновить данные песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Данные песни",
+                        "name": "song",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.SongUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Песня обновлена"
+                    },
+                    "400": {
+                        "description": "Ошибка декодирования данных или неверный ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Песня с такой группой и названием уже существует",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка обновления песни",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаление песни из библиотеки по ID.",
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Удалить песню",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Песня удалена"
+                    },
+                    "400": {
+                        "description": "Неверный ID песни",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка удаления песни",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/song/{id}/enrich": {
+            "post": {
+                "description": "Заполняет пустые дату релиза, текст и ссылку песни данными внешнего API. Заполненные поля не меняются.\nЕсли детали изменились, подписчики вебхуков получают событие song.enriched.",
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Получить детали песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Song"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID песни",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена в библиотеке или во внешнем API",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сохранения деталей",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Внешний API недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/song/{id}/text": {
+            "get": {
+                "description": "Получение текста песни по ID с возможностью пагинации по куплетам.",
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Получить текст песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы (по куплетам)",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID песни",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня или куплеты не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка получения песни",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/version": {
+            "get": {
+                "description": "Коммит, время сборки, версия Go и версия схемы базы данных, которую ожидает сборка.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Сведения о сборке",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/buildinfo.Info"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Все подписки в порядке ID, без ключей подписи.",
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Список вебхуков",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Webhook"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка получения вебхуков",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Подписка на события song.created, song.updated, song.deleted и song.enriched.\nСобытия отправляются POST-запросом с JSON-телом domain.WebhookEvent и заголовками X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp и X-Webhook-Signature.\nПодпись — sha256=\u003chex\u003e, где hex — HMAC-SHA256 с ключом secret от строки \"\u003cX-Webhook-Timestamp\u003e.\u003cтело запроса\u003e\".\nУспехом считается ответ 2xx; иначе попытки повторяются с растущей паузой. Событие может прийти повторно с тем же X-Webhook-Delivery.\nЕсли secret не задан, создается случайный ключ. Ключ возвращается только в этом ответе.",
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Создать вебхук",
+                "parameters": [
+                    {
+                        "description": "Адрес, события и ключ подписи",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.WebhookCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка декодирования или некорректные поля",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка создания вебхука",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Получить вебхук",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID вебхука",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет подписку вместе с очередью и журналом ее доставок.",
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Удалить вебхук",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Вебхук удален"
+                    },
+                    "400": {
+                        "description": "Неверный ID вебхука",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Доставки от новых к старым с состоянием, числом попыток и результатом последней попытки.\nСледующая страница запрашивается с before, равным ID последней доставки в ответе.",
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Журнал доставок вебхука",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Состояние: pending, delivered или failed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Только доставки с ID меньше указанного",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Количество доставок (до 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID вебхука или параметры",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Вебхук не найден",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{delivery_id}/redeliver": {
+            "post": {
+                "description": "Отправляет событие заново с новым циклом попыток, в том числе уже доставленное или неудачное.",
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Повторить доставку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID доставки",
+                        "name": "delivery_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/domain.WebhookDelivery"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID вебхука или доставки",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Доставка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "buildinfo.Info": {
+            "type": "object",
+            "properties": {
+                "build_time": {
+                    "type": "string",
+                    "example": "2026-10-19T06:00:00Z"
+                },
+                "commit": {
+                    "type": "string",
+                    "example": "2dda633f0c1e"
+                },
+                "go_version": {
+                    "type": "string",
+                    "example": "go1.23.2"
+                },
+                "migration_version": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "modified": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "controller.HealthCheck": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "type": "string",
+                    "example": "1.2ms"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "dial tcp: connection refused"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "controller.HealthStatus": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/controller.HealthCheck"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "controller.ImportProblem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.ErrorCode"
+                        }
+                    ],
+                    "example": "song_not_found"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "Ошибка удаления песни: песня не найдена"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/song/42"
+                },
+                "report": {
+                    "description": "Результаты строк до ошибки",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.ImportReport"
+                        }
+                    ]
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Песня не найдена"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "/errors/song_not_found"
+                }
+            }
+        },
+        "domain.BatchOperation": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "ID песни для update и delete",
+                    "type": "integer"
+                },
+                "op": {
+                    "description": "create, update или delete",
+                    "type": "string"
+                },
+                "song": {
+                    "description": "Данные песни для create (group и song) и update; для delete не нужны",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Song"
+                        }
+                    ]
+                }
+            }
+        },
+        "domain.BatchRequest": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "description": "all_or_nothing (по умолчанию) или best_effort",
+                    "type": "string"
+                },
+                "operations": {
+                    "description": "Операции в порядке выполнения",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.BatchOperation"
+                    }
+                }
+            }
+        },
+        "domain.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "committed": {
+                    "description": "Транзакция зафиксирована",
+                    "type": "boolean"
+                },
+                "failed": {
+                    "description": "Количество операций с ошибкой",
+                    "type": "integer"
+                },
+                "mode": {
+                    "description": "Режим выполнения",
+                    "type": "string"
+                },
+                "results": {
+                    "description": "Результаты по операциям",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.BatchResult"
+                    }
+                },
+                "succeeded": {
+                    "description": "Количество зафиксированных операций",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.BatchResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Причина ошибки",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID затронутой песни",
+                    "type": "integer"
+                },
+                "index": {
+                    "description": "Порядковый номер операции в запросе",
+                    "type": "integer"
+                },
+                "op": {
+                    "description": "Вид операции",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Статус выполнения",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ChangeFeed": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "description": "Изменения в порядке фиксации",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.SongChange"
+                    }
+                },
+                "has_more": {
+                    "description": "Есть еще изменения после последнего в странице",
+                    "type": "boolean"
+                },
+                "next": {
+                    "description": "Токен для следующего запроса (параметр since)",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ErrorCode": {
+            "type": "string",
+            "enum": [
+                "validation_failed",
+                "malformed_body",
+                "unsupported_format",
+                "song_not_found",
+                "verses_not_found",
+                "upstream_song_not_found",
+                "song_exists",
+                "sync_token_expired",
+                "webhook_not_found",
+                "delivery_not_found",
+                "upstream_unavailable",
+                "timeout",
+                "not_found",
+                "conflict",
+                "internal_error"
+            ],
+            "x-enum-comments": {
+                "CodeConflict": "Конфликт с текущим состоянием данных",
+                "CodeDeliveryNotFound": "Доставки с указанным ID нет у подписки",
+                "CodeInternal": "Внутренняя ошибка сервера",
+                "CodeMalformedBody": "Тело запроса не удалось разобрать",
+                "CodeNotFound": "Объект не найден",
+                "CodeSongExists": "Песня с такой группой и названием уже есть",
+                "CodeSongNotFound": "Песни с указанным ID нет",
+                "CodeSyncTokenExpired": "Токен синхронизации устарел, нужна полная загрузка",
+                "CodeTimeout": "Истекло время обработки запроса",
+                "CodeUnsupportedFormat": "Неизвестный формат импорта или экспорта",
+                "CodeUpstreamSongNotFound": "Внешний API не знает песню",
+                "CodeUpstreamUnavailable": "Внешний API недоступен или ответил ошибкой",
+                "CodeValidationFailed": "Некорректные поля запроса",
+                "CodeVersesNotFound": "Запрошенной страницы куплетов нет",
+                "CodeWebhookNotFound": "Подписки с указанным ID нет"
+            },
+            "x-enum-varnames": [
+                "CodeValidationFailed",
+                "CodeMalformedBody",
+                "CodeUnsupportedFormat",
+                "CodeSongNotFound",
+                "CodeVersesNotFound",
+                "CodeUpstreamSongNotFound",
+                "CodeSongExists",
+                "CodeSyncTokenExpired",
+                "CodeWebhookNotFound",
+                "CodeDeliveryNotFound",
+                "CodeUpstreamUnavailable",
+                "CodeTimeout",
+                "CodeNotFound",
+                "CodeConflict",
+                "CodeInternal"
+            ]
+        },
+        "domain.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "required"
+                },
+                "field": {
+                    "type": "string",
+                    "example": "group"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "не может быть пустым"
+                }
+            }
+        },
+        "domain.ImportReport": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "description": "Количество добавленных песен",
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "description": "Импорт выполнен без сохранения",
+                    "type": "boolean"
+                },
+                "failed": {
+                    "description": "Количество строк с ошибками",
+                    "type": "integer"
+                },
+                "rows": {
+                    "description": "Результаты по строкам",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ImportRowResult"
+                    }
+                },
+                "skipped_duplicate": {
+                    "description": "Количество пропущенных дубликатов",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.ImportRowResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Причина ошибки для статуса failed",
+                    "type": "string"
+                },
+                "group": {
+                    "description": "Название группы",
+                    "type": "string"
+                },
+                "line": {
+                    "description": "Номер строки во входном файле",
+                    "type": "integer"
+                },
+                "song": {
+                    "description": "Название песни",
                     "type": "string"
                 },
+                "status": {
+                    "description": "Статус обработки строки",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Song": {
+            "type": "object",
+            "required": [
+                "group",
+                "song"
+            ],
+            "properties": {
+                "created_at": {
+                    "description": "Время добавления песни",
+                    "type": "string"
+                },
+                "created_by": {
+                    "description": "Пользователь, добавивший песню",
+                    "type": "string"
+                },
+                "group": {
+                    "description": "Название группы",
+                    "type": "string",
+                    "maxLength": 255
+                },
                 "id": {
                     "description": "Уникальный идентификатор песни",
                     "type": "integer"
                 },
                 "link": {
                     "description": "Ссылка на дополнительную информацию",
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 2048
                 },
                 "release_date": {
                     "description": "Дата релиза песни",
@@ -304,24 +1440,69 @@ const docTemplate = `{
                 },
                 "song": {
                     "description": "Название песни",
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255
                 },
                 "text": {
                     "description": "Текст песни",
+                    "type": "string",
+                    "maxLength": 20000
+                },
+                "updated_at": {
+                    "description": "Время последнего изменения песни",
+                    "type": "string"
+                },
+                "updated_by": {
+                    "description": "Пользователь, последним изменивший песню",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.SongChange": {
+            "type": "object",
+            "properties": {
+                "changed_at": {
+                    "description": "Время изменения",
+                    "type": "string"
+                },
+                "group": {
+                    "description": "Группа песни; для удаления — группа удаленной песни",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID песни",
+                    "type": "integer"
+                },
+                "op": {
+                    "description": "created, updated или deleted",
                     "type": "string"
+                },
+                "song": {
+                    "description": "Песня после изменения; нет для удаления",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Song"
+                        }
+                    ]
                 }
             }
         },
         "domain.SongCreateRequest": {
             "type": "object",
+            "required": [
+                "group",
+                "song"
+            ],
             "properties": {
                 "group": {
                     "description": "Название группы (обязательно)",
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255
                 },
                 "song": {
                     "description": "Название песни (обязательно)",
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255
                 }
             }
         },
@@ -341,6 +1522,246 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "domain.SongUpdateRequest": {
+            "type": "object",
+            "required": [
+                "group",
+                "link",
+                "release_date",
+                "song",
+                "text"
+            ],
+            "properties": {
+                "group": {
+                    "description": "Название группы",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "link": {
+                    "description": "Ссылка на дополнительную информацию",
+                    "type": "string",
+                    "maxLength": 2048
+                },
+                "release_date": {
+                    "description": "Дата релиза песни",
+                    "type": "string"
+                },
+                "song": {
+                    "description": "Название песни",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "text": {
+                    "description": "Текст песни",
+                    "type": "string",
+                    "maxLength": 20000
+                }
+            }
+        },
+        "domain.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "song.created",
+                        "song.deleted"
+                    ]
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "secret": {
+                    "description": "Ключ подписи; возвращается только при создании",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://search.example.com/hooks/songs"
+                }
+            }
+        },
+        "domain.WebhookCreateRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "description": "События из WebhookEvents (хотя бы одно)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Ключ подписи; по умолчанию создается случайный",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "url": {
+                    "description": "Адрес получателя (обязательно)",
+                    "type": "string",
+                    "maxLength": 2048
+                }
+            }
+        },
+        "domain.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string",
+                    "example": "song.created"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "last_attempt_at": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "description": "Только для pending",
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "response_status": {
+                    "description": "HTTP-статус последнего ответа",
+                    "type": "integer",
+                    "example": 503
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "webhook_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "problem.CodeInfo": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.ErrorCode"
+                        }
+                    ],
+                    "example": "song_not_found"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Песня не найдена"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "/errors/song_not_found"
+                }
+            }
+        },
+        "problem.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.ErrorCode"
+                        }
+                    ],
+                    "example": "song_not_found"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "Ошибка удаления песни: песня не найдена"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/song/42"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Песня не найдена"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "/errors/song_not_found"
+                }
+            }
+        },
+        "service.CacheStats": {
+            "type": "object",
+            "properties": {
+                "collapsed": {
+                    "description": "Запросы, объединенные с уже выполняющимся",
+                    "type": "integer"
+                },
+                "errors": {
+                    "description": "Ошибки внешнего API, не попавшие в кэш",
+                    "type": "integer"
+                },
+                "hits": {
+                    "description": "Ответы из памяти или постоянного хранилища",
+                    "type": "integer"
+                },
+                "misses": {
+                    "description": "Запросы, ушедшие во внешний API",
+                    "type": "integer"
+                },
+                "negative_hits": {
+                    "description": "Попадания в отрицательные записи",
+                    "type": "integer"
+                },
+                "persistent_hits": {
+                    "description": "Попадания в постоянное хранилище",
+                    "type": "integer"
+                },
+                "size": {
+                    "description": "Количество записей в памяти",
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -352,7 +1773,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "",
 	Schemes:          []string{},
 	Title:            "Song Library API",
-	Description:      "API для управления библиотекой песен",
+	Description:      "API для управления библиотекой песен\nОшибки возвращаются в формате application/problem+json (RFC 7807).\nПоле code содержит стабильный код из каталога GET /errors, поле type ссылается на его описание.\nЯзык сообщений (ru или en) выбирается по заголовку Accept-Language, по умолчанию — DEFAULT_LANGUAGE.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
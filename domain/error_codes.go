@@ -0,0 +1,71 @@
+package domain
+
+import "errors"
+
+// ErrorCode — стабильный машиночитаемый код ошибки. Коды не меняются между
+// версиями API: клиенты опираются на них вместо текста сообщений.
+type ErrorCode string
+
+// Каталог кодов ошибок.
+const (
+	CodeValidationFailed     ErrorCode = "validation_failed"       // Некорректные поля запроса
+	CodeMalformedBody        ErrorCode = "malformed_body"          // Тело запроса не удалось разобрать
+	CodeUnsupportedFormat    ErrorCode = "unsupported_format"      // Неизвестный формат импорта или экспорта
+	CodeSongNotFound         ErrorCode = "song_not_found"          // Песни с указанным ID нет
+	CodeVersesNotFound       ErrorCode = "verses_not_found"        // Запрошенной страницы куплетов нет
+	CodeUpstreamSongNotFound ErrorCode = "upstream_song_not_found" // Внешний API не знает песню
+	CodeSongExists           ErrorCode = "song_exists"             // Песня с такой группой и названием уже есть
+	CodeSyncTokenExpired     ErrorCode = "sync_token_expired"      // Токен синхронизации устарел, нужна полная загрузка
+	CodeWebhookNotFound      ErrorCode = "webhook_not_found"       // Подписки с указанным ID нет
+	CodeDeliveryNotFound     ErrorCode = "delivery_not_found"      // Доставки с указанным ID нет у подписки
+	CodeUpstreamUnavailable  ErrorCode = "upstream_unavailable"    // Внешний API недоступен или ответил ошибкой
+	CodeTimeout              ErrorCode = "timeout"                 // Истекло время обработки запроса
+	CodeNotFound             ErrorCode = "not_found"               // Объект не найден
+	CodeConflict             ErrorCode = "conflict"                // Конфликт с текущим состоянием данных
+	CodeInternal             ErrorCode = "internal_error"          // Внутренняя ошибка сервера
+)
+
+// ErrorCodes перечисляет все коды каталога в порядке документации.
+var ErrorCodes = []ErrorCode{
+	CodeValidationFailed,
+	CodeMalformedBody,
+	CodeUnsupportedFormat,
+	CodeSongNotFound,
+	CodeVersesNotFound,
+	CodeUpstreamSongNotFound,
+	CodeSongExists,
+	CodeSyncTokenExpired,
+	CodeWebhookNotFound,
+	CodeDeliveryNotFound,
+	CodeConflict,
+	CodeNotFound,
+	CodeUpstreamUnavailable,
+	CodeTimeout,
+	CodeInternal,
+}
+
+// codedError реализуется ошибками, которые знают свой код.
+type codedError interface {
+	ErrorCode() ErrorCode
+}
+
+// CodeOf возвращает код ошибки err: собственный код первой ошибки в цепочке,
+// которая его знает, иначе общий код по виду ошибки.
+func CodeOf(err error) ErrorCode {
+	var coded codedError
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+	switch {
+	case errors.Is(err, ErrValidation):
+		return CodeValidationFailed
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrConflict):
+		return CodeConflict
+	case errors.Is(err, ErrUpstream):
+		return CodeUpstreamUnavailable
+	default:
+		return CodeInternal
+	}
+}
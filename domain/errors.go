@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+
+	"song-library/i18n"
+)
+
+// Виды ошибок приложения. Конкретные ошибки слоев сообщают свой вид через
+// errors.Is, а контроллеры выбирают по нему HTTP-статус.
+var (
+	// ErrNotFound — запрошенный объект не существует.
+	ErrNotFound = errors.New("не найдено")
+	// ErrConflict — операция нарушает ограничение уникальности или текущее состояние данных.
+	ErrConflict = errors.New("конфликт данных")
+	// ErrValidation — входные данные некорректны; подробности содержит ValidationError.
+	ErrValidation = errors.New("некорректные данные")
+	// ErrUpstream — внешний API недоступен или ответил ошибкой; подробности содержит UpstreamError.
+	ErrUpstream = errors.New("ошибка внешнего API")
+)
+
+// kindError — ошибка с собственным кодом и сообщением, относящаяся к виду kind.
+type kindError struct {
+	kind    error
+	code    ErrorCode
+	message string
+}
+
+func (e *kindError) Error() string { return e.message }
+
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+func (e *kindError) ErrorCode() ErrorCode { return e.code }
+
+// NewNotFoundError создает ошибку вида ErrNotFound с кодом code и сообщением message.
+func NewNotFoundError(code ErrorCode, message string) error {
+	return &kindError{kind: ErrNotFound, code: code, message: message}
+}
+
+// NewConflictError создает ошибку вида ErrConflict с кодом code и сообщением message.
+func NewConflictError(code ErrorCode, message string) error {
+	return &kindError{kind: ErrConflict, code: code, message: message}
+}
+
+// FieldError описывает ошибку в одном поле входных данных. Rule — стабильный
+// код нарушенного правила, Message — сообщение на языке по умолчанию.
+type FieldError struct {
+	Field   string `json:"field" example:"group"`
+	Rule    string `json:"code" example:"required"`
+	Message string `json:"message" example:"не может быть пустым"`
+	args    []any
+}
+
+// NewFieldError создает ошибку поля field по правилу rule с параметрами сообщения args.
+func NewFieldError(field, rule string, args ...any) FieldError {
+	return FieldError{Field: field, Rule: rule, Message: i18n.T(i18n.Default(), "field."+rule, args...), args: args}
+}
+
+// Localize возвращает копию ошибки с сообщением на языке lang.
+func (e FieldError) Localize(lang i18n.Language) FieldError {
+	e.Message = i18n.T(lang, "field."+e.Rule, e.args...)
+	return e
+}
+
+// ValidationError собирает ошибки по полям входных данных. Относится к виду
+// ErrValidation; пустой Code означает CodeValidationFailed.
+type ValidationError struct {
+	Code   ErrorCode
+	Fields []FieldError
+}
+
+// NewValidationError создает ошибку валидации с одной ошибкой поля.
+func NewValidationError(field, rule string, args ...any) *ValidationError {
+	return &ValidationError{Fields: []FieldError{NewFieldError(field, rule, args...)}}
+}
+
+// NewMalformedBodyError создает ошибку валидации для тела запроса, которое не удалось разобрать.
+func NewMalformedBodyError(err error) *ValidationError {
+	return &ValidationError{Code: CodeMalformedBody, Fields: []FieldError{NewFieldError("body", "malformed", err)}}
+}
+
+// Add добавляет ошибку поля field по правилу rule.
+func (e *ValidationError) Add(field, rule string, args ...any) {
+	e.Fields = append(e.Fields, NewFieldError(field, rule, args...))
+}
+
+// Localize возвращает ошибки полей с сообщениями на языке lang.
+func (e *ValidationError) Localize(lang i18n.Language) []FieldError {
+	fields := make([]FieldError, len(e.Fields))
+	for i, field := range e.Fields {
+		fields[i] = field.Localize(lang)
+	}
+	return fields
+}
+
+// Err возвращает e, если есть хотя бы одна ошибка поля, иначе nil.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	return joinFieldErrors(e.Fields)
+}
+
+func joinFieldErrors(fields []FieldError) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field.Field + ": " + field.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool { return target == ErrValidation }
+
+func (e *ValidationError) ErrorCode() ErrorCode {
+	if e.Code == "" {
+		return CodeValidationFailed
+	}
+	return e.Code
+}
+
+// UpstreamError описывает неудачный запрос к внешнему API. StatusCode равен 0,
+// если ответ не был получен.
+type UpstreamError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *UpstreamError) Error() string {
+	return "ошибка внешнего API: " + e.Err.Error()
+}
+
+func (e *UpstreamError) Unwrap() error { return e.Err }
+
+func (e *UpstreamError) Is(target error) bool { return target == ErrUpstream }
+
+func (e *UpstreamError) ErrorCode() ErrorCode { return CodeUpstreamUnavailable }
+
+// LocalizeError возвращает описание ошибки err для клиента на языке lang:
+// ошибки полей для ошибок валидации, иначе описание кода ошибки. Текст
+// внутренних ошибок клиенту не передается.
+func LocalizeError(lang i18n.Language, err error) string {
+	var validation *ValidationError
+	if errors.As(err, &validation) {
+		return joinFieldErrors(validation.Localize(lang))
+	}
+	return i18n.T(lang, "error."+string(CodeOf(err)))
+}
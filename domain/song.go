@@ -1,10 +1,30 @@
 package domain
 
+import "time"
+
+// Song представляет песню в библиотеке. Правила в тегах validate проверяет
+// пакет validation; детали песни могут быть пустыми, пока их не получили из внешнего API.
 type Song struct {
-	ID          int    `json:"id"`           // Уникальный идентификатор песни
-	Group       string `json:"group"`        // Название группы
-	Song        string `json:"song"`         // Название песни
-	ReleaseDate string `json:"release_date"` // Дата релиза песни
-	Text        string `json:"text"`         // Текст песни
-	Link        string `json:"link"`         // Ссылка на дополнительную информацию
+	ID          int    `json:"id"`                                          // Уникальный идентификатор песни
+	Group       string `json:"group" validate:"trim,required,max=255"`      // Название группы
+	Song        string `json:"song" validate:"trim,required,max=255"`       // Название песни
+	ReleaseDate string `json:"release_date" validate:"trim,omitempty,date"` // Дата релиза песни
+	Text        string `json:"text" validate:"max=20000"`                   // Текст песни
+	Link        string `json:"link" validate:"trim,omitempty,url,max=2048"` // Ссылка на дополнительную информацию
+
+	CreatedAt time.Time `json:"created_at"`           // Время добавления песни
+	UpdatedAt time.Time `json:"updated_at"`           // Время последнего изменения песни
+	CreatedBy string    `json:"created_by,omitempty"` // Пользователь, добавивший песню
+	UpdatedBy string    `json:"updated_by,omitempty"` // Пользователь, последним изменивший песню
+}
+
+// NeedsEnrichment сообщает, что у песни нет части деталей из внешнего API.
+func (s Song) NeedsEnrichment() bool {
+	return s.ReleaseDate == "" || s.Text == "" || s.Link == ""
+}
+
+// SongCounts содержит количество песен в библиотеке.
+type SongCounts struct {
+	Total             int // Всего песен
+	PendingEnrichment int // Песни, которым не хватает деталей из внешнего API
 }
@@ -0,0 +1,53 @@
+package domain
+
+// Режимы выполнения пакета операций.
+const (
+	BatchModeAllOrNothing = "all_or_nothing" // Любая ошибка откатывает весь пакет
+	BatchModeBestEffort   = "best_effort"    // Ошибочные операции откатываются по отдельности
+)
+
+// Виды операций пакета.
+const (
+	BatchOpCreate = "create" // Добавление песни
+	BatchOpUpdate = "update" // Изменение песни по ID
+	BatchOpDelete = "delete" // Удаление песни по ID
+)
+
+// Статусы результатов операций пакета.
+const (
+	BatchStatusOK         = "ok"          // Операция выполнена и зафиксирована
+	BatchStatusFailed     = "failed"      // Операция завершилась ошибкой
+	BatchStatusRolledBack = "rolled_back" // Операция выполнена, но откачена из-за ошибки другой операции
+	BatchStatusSkipped    = "skipped"     // Операция не выполнялась из-за ошибки другой операции
+)
+
+// BatchRequest представляет пакет операций над песнями.
+type BatchRequest struct {
+	Mode       string           `json:"mode"`       // all_or_nothing (по умолчанию) или best_effort
+	Operations []BatchOperation `json:"operations"` // Операции в порядке выполнения
+}
+
+// BatchOperation представляет одну операцию пакета.
+type BatchOperation struct {
+	Op   string `json:"op"`             // create, update или delete
+	ID   int    `json:"id,omitempty"`   // ID песни для update и delete
+	Song *Song  `json:"song,omitempty"` // Данные песни для create (group и song) и update; для delete не нужны
+}
+
+// BatchResult описывает результат одной операции пакета.
+type BatchResult struct {
+	Index  int    `json:"index"`           // Порядковый номер операции в запросе
+	Op     string `json:"op"`              // Вид операции
+	ID     int    `json:"id,omitempty"`    // ID затронутой песни
+	Status string `json:"status"`          // Статус выполнения
+	Error  string `json:"error,omitempty"` // Причина ошибки
+}
+
+// BatchResponse представляет итог выполнения пакета операций.
+type BatchResponse struct {
+	Mode      string        `json:"mode"`      // Режим выполнения
+	Committed bool          `json:"committed"` // Транзакция зафиксирована
+	Succeeded int           `json:"succeeded"` // Количество зафиксированных операций
+	Failed    int           `json:"failed"`    // Количество операций с ошибкой
+	Results   []BatchResult `json:"results"`   // Результаты по операциям
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// Виды изменений в журнале изменений песен.
+const (
+	ChangeCreated = "created" // Песня добавлена
+	ChangeUpdated = "updated" // Данные песни изменены
+	ChangeDeleted = "deleted" // Песня удалена
+)
+
+// SongChange описывает запись журнала изменений песен.
+type SongChange struct {
+	Seq       int64     `json:"-"`              // Номер изменения в журнале
+	Op        string    `json:"op"`             // created, updated или deleted
+	ID        int       `json:"id"`             // ID песни
+	Group     string    `json:"group"`          // Группа песни; для удаления — группа удаленной песни
+	Song      *Song     `json:"song,omitempty"` // Песня после изменения; нет для удаления
+	ChangedAt time.Time `json:"changed_at"`     // Время изменения
+}
+
+// ChangeLogState описывает журнал изменений: его идентификатор и границы
+// доступных номеров изменений.
+type ChangeLogState struct {
+	LogID         string // Идентификатор журнала; меняется, например, при восстановлении из архива
+	PrunedThrough int64  // Наибольший номер изменения, удаленного по сроку хранения
+	Latest        int64  // Номер последнего изменения
+}
+
+// ChangeFeed — страница журнала изменений для синхронизации клиента.
+type ChangeFeed struct {
+	Changes []SongChange `json:"changes"`  // Изменения в порядке фиксации
+	Next    string       `json:"next"`     // Токен для следующего запроса (параметр since)
+	HasMore bool         `json:"has_more"` // Есть еще изменения после последнего в странице
+}
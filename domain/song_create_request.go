@@ -2,6 +2,6 @@ package domain
 
 // SongCreateRequest представляет данные, которые клиент отправляет для добавления новой песни.
 type SongCreateRequest struct {
-	Group string `json:"group"` // Название группы (обязательно)
-	Song  string `json:"song"`  // Название песни (обязательно)
+	Group string `json:"group" validate:"trim,required,max=255"` // Название группы (обязательно)
+	Song  string `json:"song" validate:"trim,required,max=255"`  // Название песни (обязательно)
 }
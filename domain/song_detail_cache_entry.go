@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// SongDetailCacheEntry представляет сохраненный ответ внешнего API.
+type SongDetailCacheEntry struct {
+	Detail    *SongDetail // Детали песни, nil для отрицательного результата
+	NotFound  bool        // Внешний API ответил, что песня не найдена
+	ExpiresAt time.Time   // Время устаревания записи
+}
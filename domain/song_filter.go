@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// SongFilter задает условия отбора песен. Пустые поля не ограничивают выборку.
+type SongFilter struct {
+	Group        string    // Подстрока названия группы
+	Song         string    // Подстрока названия песни
+	ReleaseDate  string    // Точная дата релиза
+	UpdatedSince time.Time // Песни, измененные строго позже этого времени
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "sort"
+
+// Статусы строк отчета об импорте.
+const (
+	ImportStatusCreated          = "created"           // Песня добавлена
+	ImportStatusSkippedDuplicate = "skipped_duplicate" // Песня уже есть в библиотеке или встречалась выше в файле
+	ImportStatusFailed           = "failed"            // Строка не прошла проверку или не была сохранена
+)
+
+// ImportOptions задает параметры импорта песен.
+type ImportOptions struct {
+	DryRun    bool // Проверить файл без сохранения изменений
+	BatchSize int  // Количество строк в одной транзакции
+	Enrich    bool // Запрашивать недостающие детали во внешнем API
+}
+
+// ImportRowResult описывает результат обработки одной строки импорта.
+type ImportRowResult struct {
+	Line   int    `json:"line"`            // Номер строки во входном файле
+	Group  string `json:"group"`           // Название группы
+	Song   string `json:"song"`            // Название песни
+	Status string `json:"status"`          // Статус обработки строки
+	Error  string `json:"error,omitempty"` // Причина ошибки для статуса failed
+}
+
+// ImportReport представляет итог импорта песен.
+type ImportReport struct {
+	DryRun           bool              `json:"dry_run"`           // Импорт выполнен без сохранения
+	Created          int               `json:"created"`           // Количество добавленных песен
+	SkippedDuplicate int               `json:"skipped_duplicate"` // Количество пропущенных дубликатов
+	Failed           int               `json:"failed"`            // Количество строк с ошибками
+	Rows             []ImportRowResult `json:"rows"`              // Результаты по строкам
+}
+
+// Add добавляет результат строки в отчет и обновляет счетчики.
+func (report *ImportReport) Add(row ImportRowResult) {
+	switch row.Status {
+	case ImportStatusCreated:
+		report.Created++
+	case ImportStatusSkippedDuplicate:
+		report.SkippedDuplicate++
+	case ImportStatusFailed:
+		report.Failed++
+	}
+	report.Rows = append(report.Rows, row)
+}
+
+// SortRows упорядочивает результаты по номерам строк: строки с ошибками
+// попадают в отчет сразу, а остальные — при сохранении их пакета.
+func (report *ImportReport) SortRows() {
+	sort.SliceStable(report.Rows, func(i, j int) bool { return report.Rows[i].Line < report.Rows[j].Line })
+}
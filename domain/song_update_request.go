@@ -0,0 +1,23 @@
+package domain
+
+// SongUpdateRequest представляет данные, которые клиент отправляет для изменения песни.
+// Все поля заменяются, поэтому все они обязательны.
+type SongUpdateRequest struct {
+	Group       string `json:"group" validate:"trim,required,max=255"`     // Название группы
+	Song        string `json:"song" validate:"trim,required,max=255"`      // Название песни
+	ReleaseDate string `json:"release_date" validate:"trim,required,date"` // Дата релиза песни
+	Text        string `json:"text" validate:"required,max=20000"`         // Текст песни
+	Link        string `json:"link" validate:"trim,required,url,max=2048"` // Ссылка на дополнительную информацию
+}
+
+// ToSong возвращает песню с идентификатором id и данными запроса.
+func (request SongUpdateRequest) ToSong(id int) Song {
+	return Song{
+		ID:          id,
+		Group:       request.Group,
+		Song:        request.Song,
+		ReleaseDate: request.ReleaseDate,
+		Text:        request.Text,
+		Link:        request.Link,
+	}
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// События жизненного цикла песен, на которые подписываются вебхуки.
+const (
+	EventSongCreated  = "song.created"  // Песня добавлена
+	EventSongUpdated  = "song.updated"  // Данные песни изменены
+	EventSongDeleted  = "song.deleted"  // Песня удалена
+	EventSongEnriched = "song.enriched" // Недостающие детали песни получены из внешнего API
+)
+
+// WebhookEvents перечисляет события, доступные для подписки.
+var WebhookEvents = []string{EventSongCreated, EventSongUpdated, EventSongDeleted, EventSongEnriched}
+
+// Webhook описывает подписку на события песен.
+type Webhook struct {
+	ID        int       `json:"id" example:"1"`
+	URL       string    `json:"url" example:"https://search.example.com/hooks/songs"`
+	Events    []string  `json:"events" example:"song.created,song.deleted"`
+	Secret    string    `json:"secret,omitempty"` // Ключ подписи; возвращается только при создании
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+}
+
+// Subscribed сообщает, что подписка получает событие event.
+func (w Webhook) Subscribed(event string) bool {
+	for _, subscribed := range w.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookCreateRequest представляет данные для создания подписки.
+type WebhookCreateRequest struct {
+	URL    string   `json:"url" validate:"trim,required,url,max=2048"` // Адрес получателя (обязательно)
+	Events []string `json:"events"`                                    // События из WebhookEvents (хотя бы одно)
+	Secret string   `json:"secret" validate:"trim,omitempty,max=255"`  // Ключ подписи; по умолчанию создается случайный
+}
+
+// WebhookEvent — тело запроса, отправляемого подписчику.
+type WebhookEvent struct {
+	Event      string    `json:"event" example:"song.updated"`
+	SongID     int       `json:"song_id" example:"42"`
+	Song       *Song     `json:"song,omitempty"` // Песня после изменения; нет для удаления
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Состояния доставки события подписчику.
+const (
+	DeliveryPending   = "pending"   // Ожидает первой или повторной попытки
+	DeliveryDelivered = "delivered" // Получатель ответил статусом 2xx
+	DeliveryFailed    = "failed"    // Попытки исчерпаны
+)
+
+// WebhookDelivery описывает доставку одного события одной подписке.
+type WebhookDelivery struct {
+	ID             int64           `json:"id" example:"100"`
+	WebhookID      int             `json:"webhook_id" example:"1"`
+	Event          string          `json:"event" example:"song.created"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status" example:"pending"`
+	Attempts       int             `json:"attempts" example:"2"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"` // Только для pending
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty" example:"503"` // HTTP-статус последнего ответа
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// DeliveryAttempt — результат попытки доставки.
+type DeliveryAttempt struct {
+	DeliveryID     int64
+	At             time.Time // Время попытки
+	ResponseStatus int       // HTTP-статус ответа, 0 — ответа не было
+	Error          string    // Описание ошибки для неуспешной попытки
+	Delivered      bool      // Получатель принял событие
+	// NextAttemptAt — время повторной попытки; нулевое значение означает,
+	// что попытки исчерпаны и доставка завершается с ошибкой.
+	NextAttemptAt time.Time
+}
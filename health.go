@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"song-library/config"
+	"song-library/controller"
+	"song-library/migrations"
+)
+
+// readinessChecks перечисляет проверки готовности приложения: соединение с
+// базой данных и версию схемы, а при READINESS_CHECK_UPSTREAM=true еще и
+// доступность внешнего API. Хранилищу в памяти проверки базы не нужны.
+func (app *application) readinessChecks(cfg *config.Config, migrationVersion uint) []controller.ReadinessCheck {
+	var checks []controller.ReadinessCheck
+	if app.db != nil {
+		checks = append(checks,
+			controller.ReadinessCheck{Name: "database", Check: app.db.PingContext},
+			controller.ReadinessCheck{Name: "migrations", Check: func(ctx context.Context) error {
+				return app.checkSchemaVersion(ctx, migrationVersion)
+			}},
+		)
+	}
+	if cfg.ReadinessCheckUpstream {
+		checks = append(checks, controller.ReadinessCheck{Name: "upstream", Check: app.songService.PingUpstream})
+	}
+	return checks
+}
+
+// checkSchemaVersion сверяет версию схемы базы данных с ожидаемой сборкой.
+func (app *application) checkSchemaVersion(ctx context.Context, expected uint) error {
+	version, dirty, err := migrations.CurrentVersion(ctx, app.db)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения версии схемы: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("миграция %d завершилась с ошибкой, схема требует исправления", version)
+	}
+	if version != expected {
+		return fmt.Errorf("версия схемы %d, ожидается %d", version, expected)
+	}
+	return nil
+}
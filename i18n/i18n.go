@@ -0,0 +1,137 @@
+// Package i18n содержит каталоги сообщений API на русском и английском языках
+// и выбор языка по заголовку Accept-Language.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Language — код языка сообщений (основной подтег BCP 47).
+type Language string
+
+// Поддерживаемые языки.
+const (
+	Russian Language = "ru"
+	English Language = "en"
+)
+
+// bundles содержит каталоги сообщений по языкам. Ключи: title.<код ошибки>,
+// error.<код ошибки>, field.<правило проверки> и op.<операция>.
+var bundles = map[Language]map[string]string{
+	Russian: russian,
+	English: english,
+}
+
+// defaultLanguage используется, когда клиент не указал поддерживаемый язык.
+var defaultLanguage atomic.Value
+
+func init() {
+	defaultLanguage.Store(Russian)
+}
+
+// ParseLanguage проверяет, что язык поддерживается.
+func ParseLanguage(name string) (Language, error) {
+	lang := Language(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := bundles[lang]; !ok {
+		return "", fmt.Errorf("неподдерживаемый язык: %q", name)
+	}
+	return lang, nil
+}
+
+// SetDefault задает язык по умолчанию.
+func SetDefault(lang Language) {
+	defaultLanguage.Store(lang)
+}
+
+// Default возвращает язык по умолчанию.
+func Default() Language {
+	return defaultLanguage.Load().(Language)
+}
+
+// T возвращает сообщение key на языке lang, подставляя args. Если в каталоге
+// языка сообщения нет, используется русский каталог, а при его отсутствии — сам ключ.
+func T(lang Language, key string, args ...any) string {
+	message, ok := bundles[lang][key]
+	if !ok {
+		message, ok = bundles[Russian][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Negotiate выбирает язык по заголовку Accept-Language с учетом весов q.
+// Региональные варианты (en-US) сводятся к основному языку.
+func Negotiate(acceptLanguage string) Language {
+	type candidate struct {
+		lang    Language
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		lang := Language(primary)
+		if primary == "*" {
+			lang = Default()
+		}
+		if _, ok := bundles[lang]; ok {
+			candidates = append(candidates, candidate{lang: lang, quality: quality})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Default()
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	return candidates[0].lang
+}
+
+type contextKey struct{}
+
+// WithLanguage возвращает контекст с выбранным языком.
+func WithLanguage(ctx context.Context, lang Language) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// FromContext возвращает язык из контекста или язык по умолчанию.
+func FromContext(ctx context.Context) Language {
+	if lang, ok := ctx.Value(contextKey{}).(Language); ok {
+		return lang
+	}
+	return Default()
+}
+
+// Middleware выбирает язык ответа по заголовку Accept-Language и передает его
+// обработчику через контекст запроса.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := Negotiate(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", string(lang))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(WithLanguage(r.Context(), lang)))
+	})
+}
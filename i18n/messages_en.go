@@ -0,0 +1,89 @@
+package i18n
+
+var english = map[string]string{
+	// Заголовки ошибок по кодам каталога
+	"title.validation_failed":       "Invalid request parameters",
+	"title.malformed_body":          "Malformed request body",
+	"title.unsupported_format":      "Unsupported format",
+	"title.song_not_found":          "Song not found",
+	"title.verses_not_found":        "Verses not found",
+	"title.upstream_song_not_found": "Song not found in the external API",
+	"title.song_exists":             "Song already exists",
+	"title.sync_token_expired":      "Sync token expired",
+	"title.webhook_not_found":       "Webhook not found",
+	"title.delivery_not_found":      "Delivery not found",
+	"title.conflict":                "Data conflict",
+	"title.not_found":               "Not found",
+	"title.upstream_unavailable":    "External API unavailable",
+	"title.timeout":                 "Request timed out",
+	"title.internal_error":          "Internal server error",
+
+	// Описания ошибок по кодам каталога
+	"error.validation_failed":       "invalid data",
+	"error.malformed_body":          "the request body could not be parsed",
+	"error.unsupported_format":      "unsupported format",
+	"error.song_not_found":          "song not found",
+	"error.verses_not_found":        "verses not found",
+	"error.upstream_song_not_found": "song not found in the external API",
+	"error.song_exists":             "a song with this group and title already exists",
+	"error.webhook_not_found":       "webhook not found",
+	"error.delivery_not_found":      "delivery not found",
+	"error.sync_token_expired":      "changes after this token are no longer in the log: get a new token from /changes without since and download the library again",
+	"error.conflict":                "data conflict",
+	"error.not_found":               "not found",
+	"error.upstream_unavailable":    "the external API is unavailable or returned an error",
+	"error.timeout":                 "the request timed out",
+	"error.internal_error":          "internal server error",
+
+	// Ошибки полей
+	"field.required":            "must not be empty",
+	"field.max":                 "must be at most %d characters long",
+	"field.url":                 "must be an http or https link",
+	"field.date":                "must be a date in DD.MM.YYYY or YYYY-MM-DD format",
+	"field.positive":            "must be positive, got %v",
+	"field.invalid_id":          "invalid song ID: %v",
+	"field.invalid_webhook_id":  "invalid webhook ID: %v",
+	"field.invalid_delivery_id": "invalid delivery ID: %v",
+	"field.unknown_value":       "unknown value %q",
+	"field.batch_size":          "a batch must contain from 1 to %d operations, got %d",
+	"field.malformed":           "could not be parsed: %v",
+	"field.trailing_data":       "unexpected data after the JSON object",
+	"field.unsupported_format":  "unsupported format: %q",
+	"field.format_required":     "specify the format in the 'format' parameter or the Content-Type header",
+	"field.sync_token":          "must be a token from the next field of a /changes response",
+	"field.timestamp":           "must be a time in RFC 3339 format, for example 2024-01-02T15:04:05Z",
+
+	// Операции, при которых произошла ошибка
+	"op.get_library":        "Failed to get the library",
+	"op.invalid_song_id":    "Invalid song ID",
+	"op.get_song":           "Failed to get the song",
+	"op.get_song_text":      "Failed to get the song text",
+	"op.delete_song":        "Failed to delete the song",
+	"op.decode_song":        "Failed to decode the song data",
+	"op.update_song":        "Failed to update the song",
+	"op.add_song":           "Failed to add the song",
+	"op.decode_batch":       "Failed to decode the batch",
+	"op.execute_batch":      "Failed to execute the batch",
+	"op.invalid_format":     "Invalid format",
+	"op.invalid_filter":     "Invalid filter",
+	"op.get_changes":        "Failed to get changes",
+	"op.subscribe_events":   "Failed to subscribe to events",
+	"op.enrich_song":        "Failed to get the song details",
+	"op.decode_webhook":     "Failed to decode the webhook data",
+	"op.invalid_webhook_id": "Invalid webhook ID",
+	"op.create_webhook":     "Failed to create the webhook",
+	"op.get_webhooks":       "Failed to get webhooks",
+	"op.get_webhook":        "Failed to get the webhook",
+	"op.delete_webhook":     "Failed to delete the webhook",
+	"op.get_deliveries":     "Failed to get the delivery log",
+	"op.redeliver":          "Failed to redeliver",
+	"op.read_import":        "Failed to read the import file",
+	"op.import_songs":       "Failed to import songs",
+	"op.export_songs":       "Failed to export songs",
+	"op.get_error_code":     "Failed to get the error code description",
+	"op.invalid_request":    "Invalid request",
+	"op.handle_request":     "Failed to handle the request",
+
+	// Import row results
+	"import.not_saved": "not saved: the import stopped before its batch was written",
+}
@@ -0,0 +1,89 @@
+package i18n
+
+var russian = map[string]string{
+	// Заголовки ошибок по кодам каталога
+	"title.validation_failed":       "Некорректные параметры запроса",
+	"title.malformed_body":          "Не удалось разобрать тело запроса",
+	"title.unsupported_format":      "Неподдерживаемый формат",
+	"title.song_not_found":          "Песня не найдена",
+	"title.verses_not_found":        "Куплеты не найдены",
+	"title.upstream_song_not_found": "Песня не найдена во внешнем API",
+	"title.song_exists":             "Песня уже существует",
+	"title.sync_token_expired":      "Токен синхронизации устарел",
+	"title.webhook_not_found":       "Вебхук не найден",
+	"title.delivery_not_found":      "Доставка не найдена",
+	"title.conflict":                "Конфликт данных",
+	"title.not_found":               "Объект не найден",
+	"title.upstream_unavailable":    "Внешний API недоступен",
+	"title.timeout":                 "Истекло время обработки запроса",
+	"title.internal_error":          "Внутренняя ошибка сервера",
+
+	// Описания ошибок по кодам каталога
+	"error.validation_failed":       "некорректные данные",
+	"error.malformed_body":          "не удалось разобрать тело запроса",
+	"error.unsupported_format":      "неподдерживаемый формат",
+	"error.song_not_found":          "песня не найдена",
+	"error.verses_not_found":        "куплеты не найдены",
+	"error.upstream_song_not_found": "песня не найдена во внешнем API",
+	"error.song_exists":             "песня с такой группой и названием уже существует",
+	"error.webhook_not_found":       "вебхук не найден",
+	"error.delivery_not_found":      "доставка не найдена",
+	"error.sync_token_expired":      "изменения после этого токена уже удалены из журнала: получите новый токен запросом /changes без since и загрузите библиотеку заново",
+	"error.conflict":                "конфликт данных",
+	"error.not_found":               "объект не найден",
+	"error.upstream_unavailable":    "внешний API недоступен или ответил ошибкой",
+	"error.timeout":                 "истекло время обработки запроса",
+	"error.internal_error":          "внутренняя ошибка сервера",
+
+	// Ошибки полей
+	"field.required":            "не может быть пустым",
+	"field.max":                 "не может быть длиннее %d символов",
+	"field.url":                 "должно быть ссылкой http или https",
+	"field.date":                "должно быть датой в формате ДД.ММ.ГГГГ или ГГГГ-ММ-ДД",
+	"field.positive":            "должно быть положительным, получено %v",
+	"field.invalid_id":          "некорректный ID песни: %v",
+	"field.invalid_webhook_id":  "некорректный ID вебхука: %v",
+	"field.invalid_delivery_id": "некорректный ID доставки: %v",
+	"field.unknown_value":       "неизвестное значение %q",
+	"field.batch_size":          "пакет должен содержать от 1 до %d операций, получено %d",
+	"field.malformed":           "не удалось разобрать: %v",
+	"field.trailing_data":       "после JSON-объекта есть лишние данные",
+	"field.unsupported_format":  "неподдерживаемый формат: %q",
+	"field.format_required":     "укажите формат в параметре 'format' или заголовке Content-Type",
+	"field.sync_token":          "должно быть токеном из поля next ответа /changes",
+	"field.timestamp":           "должно быть временем в формате RFC 3339, например 2024-01-02T15:04:05Z",
+
+	// Операции, при которых произошла ошибка
+	"op.get_library":        "Ошибка получения библиотеки",
+	"op.invalid_song_id":    "Неверный ID песни",
+	"op.get_song":           "Ошибка получения песни",
+	"op.get_song_text":      "Ошибка получения текста песни",
+	"op.delete_song":        "Ошибка удаления песни",
+	"op.decode_song":        "Ошибка декодирования данных песни",
+	"op.update_song":        "Ошибка обновления песни",
+	"op.add_song":           "Ошибка добавления песни",
+	"op.decode_batch":       "Ошибка декодирования пакета операций",
+	"op.execute_batch":      "Ошибка выполнения пакета",
+	"op.invalid_format":     "Некорректный формат",
+	"op.invalid_filter":     "Некорректный фильтр",
+	"op.get_changes":        "Ошибка получения изменений",
+	"op.subscribe_events":   "Ошибка подписки на события",
+	"op.enrich_song":        "Ошибка получения деталей песни",
+	"op.decode_webhook":     "Ошибка декодирования данных вебхука",
+	"op.invalid_webhook_id": "Неверный ID вебхука",
+	"op.create_webhook":     "Ошибка создания вебхука",
+	"op.get_webhooks":       "Ошибка получения вебхуков",
+	"op.get_webhook":        "Ошибка получения вебхука",
+	"op.delete_webhook":     "Ошибка удаления вебхука",
+	"op.get_deliveries":     "Ошибка получения журнала доставок",
+	"op.redeliver":          "Ошибка повторной доставки",
+	"op.read_import":        "Ошибка чтения файла импорта",
+	"op.import_songs":       "Ошибка импорта песен",
+	"op.export_songs":       "Ошибка экспорта песен",
+	"op.get_error_code":     "Ошибка получения описания кода",
+	"op.invalid_request":    "Некорректный запрос",
+	"op.handle_request":     "Ошибка обработки запроса",
+
+	// Результаты строк импорта
+	"import.not_saved": "не сохранено: импорт прерван до записи пакета",
+}
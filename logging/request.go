@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// requestFields — поля текущего запроса, которые добавляются к каждой записи
+// журнала: request_id, method, route, song_id и duration от начала запроса.
+type requestFields struct {
+	mu     sync.Mutex
+	id     string
+	method string
+	route  string
+	start  time.Time
+	songID int
+}
+
+type contextKey struct{}
+
+// WithRequest возвращает контекст с полями журнала для запроса r с идентификатором requestID.
+func WithRequest(ctx context.Context, requestID string, r *http.Request) context.Context {
+	fields := &requestFields{id: requestID, method: r.Method, start: time.Now()}
+	return context.WithValue(ctx, contextKey{}, fields)
+}
+
+// SetRoute запоминает шаблон маршрута текущего запроса.
+func SetRoute(ctx context.Context, route string) {
+	if fields := requestFieldsFrom(ctx); fields != nil {
+		fields.mu.Lock()
+		fields.route = route
+		fields.mu.Unlock()
+	}
+}
+
+// Route возвращает шаблон маршрута текущего запроса или пустую строку.
+func Route(ctx context.Context) string {
+	if fields := requestFieldsFrom(ctx); fields != nil {
+		fields.mu.Lock()
+		defer fields.mu.Unlock()
+		return fields.route
+	}
+	return ""
+}
+
+// SetSongID запоминает ID песни, с которой работает текущий запрос.
+func SetSongID(ctx context.Context, id int) {
+	if fields := requestFieldsFrom(ctx); fields != nil {
+		fields.mu.Lock()
+		fields.songID = id
+		fields.mu.Unlock()
+	}
+}
+
+// RequestID возвращает идентификатор текущего запроса или пустую строку.
+func RequestID(ctx context.Context) string {
+	if fields := requestFieldsFrom(ctx); fields != nil {
+		return fields.id
+	}
+	return ""
+}
+
+// NewRequestID создает случайный идентификатор запроса.
+func NewRequestID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id[:])
+}
+
+func requestFieldsFrom(ctx context.Context) *requestFields {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(contextKey{}).(*requestFields)
+	return fields
+}
+
+func (f *requestFields) attrs() []slog.Attr {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	attrs := []slog.Attr{
+		slog.String("request_id", f.id),
+		slog.String("method", f.method),
+		slog.String("route", f.route),
+		slog.Duration("duration", time.Since(f.start)),
+	}
+	if f.songID != 0 {
+		attrs = append(attrs, slog.Int("song_id", f.songID))
+	}
+	return attrs
+}
+
+// Routes передает запрос в mux, предварительно записав в поля журнала шаблон
+// выбранного маршрута, чтобы он был в записях обработчика.
+func Routes(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		SetRoute(r.Context(), pattern)
+		mux.ServeHTTP(w, r)
+	})
+}
@@ -1,74 +1,372 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"song-library/api"
+	"song-library/auth"
+	"song-library/buildinfo"
+	"song-library/config"
 	"song-library/controller"
 	_ "song-library/docs"
+	"song-library/i18n"
+	"song-library/logging"
+	"song-library/metrics"
+	"song-library/middleware"
 	"song-library/migrations"
 	"song-library/repository"
 	"song-library/service"
+	"song-library/tracing"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 // @title			Song Library API
 // @version		1.0
 // @description	API для управления библиотекой песен
+// @description	Ошибки возвращаются в формате application/problem+json (RFC 7807).
+// @description	Поле code содержит стабильный код из каталога GET /errors, поле type ссылается на его описание.
+// @description	Язык сообщений (ru или en) выбирается по заголовку Accept-Language, по умолчанию — DEFAULT_LANGUAGE.
 // @termsOfService	http://swagger.io/terms/
 func main() {
-	// Загрузка .env файла
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Не удалось загрузить .env файл: %v", err)
+	// Настройки из значений по умолчанию, CONFIG_FILE, .env и окружения.
+	// Команда config print выводит их даже при ошибках проверки.
+	cfg, err := config.Load()
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if cmdErr := runConfigCommand(cfg, err, os.Args[2:]); cmdErr != nil {
+			log.Fatal(cmdErr)
+		}
+		return
 	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Журнал в формате JSON; стандартный log пишет через него же
+	logger := newLogger(cfg.LogLevel)
+	slog.SetDefault(logger)
+	slog.SetLogLoggerLevel(slog.LevelError)
 
-	// Чтение конфигурационных переменных из окружения
-	dbURL := os.Getenv("DB_URL")
-	apiBaseURL := os.Getenv("API_BASE_URL")
-	appPort := os.Getenv("APP_PORT")
-	if appPort == "" {
-		appPort = "8080" // Значение по умолчанию
+	// Ошибки после настройки трассировки завершают процесс с кодом 1 только
+	// после отложенных вызовов: закрытия базы данных и отправки спанов
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// Трассировка OpenTelemetry; экспортер выбирается настройкой OTEL_TRACES_EXPORTER
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracesExporter)
+	if err != nil {
+		log.Fatalf("Ошибка настройки трассировки: %v", err)
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("ошибка завершения экспорта спанов", "error", err)
+		}
+	}()
 
-	// Подключение к базе данных
-	db, err := sql.Open("postgres", dbURL)
+	// Язык сообщений для клиентов, не указавших Accept-Language
+	lang, err := i18n.ParseLanguage(cfg.DefaultLanguage)
 	if err != nil {
-		log.Fatalf("Ошибка подключения к базе данных: %v", err)
+		log.Fatalf("Некорректная настройка DEFAULT_LANGUAGE: %v", err)
+	}
+	i18n.SetDefault(lang)
+
+	// SIGINT и SIGTERM прерывают команды CLI, ожидание базы данных при
+	// запуске сервера и его работу
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Подкоманды CLI (например, import); без аргументов запускается сервер
+	if len(os.Args) > 1 {
+		if err := runCommand(ctx, cfg, os.Args[1], os.Args[2:]); err != nil {
+			logger.Error("ошибка выполнения команды", "command", os.Args[1], "error", err)
+			exitCode = 1
+		}
+		return
 	}
-	defer db.Close()
 
-	// Выполнение миграций
-	migrations.RunMigrations(dbURL)
+	app := newApplication(ctx, cfg)
+	defer app.Close()
 
-	// Логгер
-	logger := log.New(os.Stdout, "SONG-APP: ", log.LstdFlags|log.Lshortfile)
+	// Контроллеры
+	songController := controller.NewSongController(app.songService)
+	transferController := controller.NewTransferController(app.songService)
+	webhookController := controller.NewWebhookController(app.webhookService)
+	eventController := controller.NewEventController(app.changeStream, cfg.EventsHeartbeat)
+	infoController := api.NewInfoController(app.songService)
+	build := buildinfo.Get(app.migrationVersion)
+	healthController := controller.NewHealthController(build, app.readinessChecks(cfg, build.MigrationVersion)...)
 
-	// Репозиторий, сервис и контроллер
-	repo := repository.NewSongRepository(db, logger)
-	songService := service.NewSongService(repo, logger, apiBaseURL)
-	songController := controller.NewSongController(songService)
-	infoController := api.NewInfoController(songService)
+	// Предельное время обработки запросов: QUERY_TIMEOUT для всех маршрутов,
+	// QUERY_TIMEOUT_<МАРШРУТ> для отдельного маршрута. Импорт и экспорт по
+	// умолчанию не ограничены, так как их длительность зависит от объема данных.
+	queryTimeout := cfg.QueryTimeout
+	deadline := func(route string, fallback time.Duration, handler http.HandlerFunc) http.Handler {
+		return middleware.Timeout(cfg.RouteTimeout(route, fallback))(handler)
+	}
 
 	// Настройка маршрутов
 	mux := http.NewServeMux()
-	mux.HandleFunc("GET /library", songController.GetLibraryHandler)         // Получение библиотеки с фильтрацией и пагинацией
-	mux.HandleFunc("GET /song/{id}/text", songController.GetSongTextHandler) // Получение текста песни с пагинацией по куплетам
-	mux.HandleFunc("DELETE /song/{id}", songController.DeleteSongHandler)    // Удаление песни
-	mux.HandleFunc("PUT /song/{id}", songController.UpdateSongHandler)       // Изменение данных песни
-	mux.HandleFunc("POST /song", songController.AddSongHandler)              // Добавление новой песни
+	mux.Handle("GET /library", deadline("LIBRARY", queryTimeout, songController.GetLibraryHandler))               // Получение библиотеки с фильтрацией и пагинацией
+	mux.Handle("GET /song/{id}/text", deadline("SONG_TEXT", queryTimeout, songController.GetSongTextHandler))     // Получение текста песни с пагинацией по куплетам
+	mux.Handle("DELETE /song/{id}", deadline("SONG_DELETE", queryTimeout, songController.DeleteSongHandler))      // Удаление песни
+	mux.Handle("PUT /song/{id}", deadline("SONG_UPDATE", queryTimeout, songController.UpdateSongHandler))         // Изменение данных песни
+	mux.Handle("POST /song", deadline("SONG_CREATE", queryTimeout, songController.AddSongHandler))                // Добавление новой песни
+	mux.Handle("POST /song/{id}/enrich", deadline("SONG_ENRICH", queryTimeout, songController.EnrichSongHandler)) // Получение недостающих деталей песни
+	mux.Handle("POST /song/batch", deadline("SONG_BATCH", 30*time.Second, songController.BatchHandler))           // Пакетное изменение песен в одной транзакции
+	mux.Handle("POST /import", deadline("IMPORT", 0, transferController.ImportHandler))                           // Массовый импорт песен из CSV или NDJSON
+	mux.Handle("GET /export", deadline("EXPORT", 0, transferController.ExportHandler))                            // Потоковая выгрузка библиотеки
+	mux.Handle("GET /changes", deadline("CHANGES", queryTimeout, songController.ChangesHandler))                  // Изменения библиотеки после токена синхронизации
+	mux.HandleFunc("GET /events", eventController.EventsHandler)                                                  // Поток изменений библиотеки (Server-Sent Events)
+	mux.HandleFunc("GET /errors", controller.ErrorCatalogHandler)                                                 // Каталог кодов ошибок
+	mux.HandleFunc("GET /errors/{code}", controller.ErrorCodeHandler)                                             // Описание кода ошибки
 	mux.HandleFunc("GET /swagger/", httpSwagger.WrapHandler)
+	mux.Handle("GET /metrics", app.metrics.Handler())                // Метрики в формате Prometheus
+	mux.HandleFunc("GET /healthz", healthController.LivenessHandler) // Проверка живости
+	mux.HandleFunc("GET /readyz", healthController.ReadinessHandler) // Проверка готовности
+	mux.HandleFunc("GET /version", healthController.VersionHandler)  // Сведения о сборке
 	// Внешний API
 	mux.HandleFunc("GET /info", infoController.InfoHandler)
+	mux.HandleFunc("GET /info/cache", infoController.CacheStatsHandler) // Счетчики кэша деталей песен
+	// Вебхуки
+	mux.Handle("POST /webhooks", deadline("WEBHOOKS", queryTimeout, webhookController.CreateWebhookHandler))                                     // Подписка на события песен
+	mux.Handle("GET /webhooks", deadline("WEBHOOKS", queryTimeout, webhookController.GetWebhooksHandler))                                        // Список подписок
+	mux.Handle("GET /webhooks/{id}", deadline("WEBHOOKS", queryTimeout, webhookController.GetWebhookHandler))                                    // Подписка по ID
+	mux.Handle("DELETE /webhooks/{id}", deadline("WEBHOOKS", queryTimeout, webhookController.DeleteWebhookHandler))                              // Удаление подписки
+	mux.Handle("GET /webhooks/{id}/deliveries", deadline("WEBHOOKS", queryTimeout, webhookController.GetDeliveriesHandler))                      // Журнал доставок
+	mux.Handle("POST /webhooks/{id}/deliveries/{delivery_id}/redeliver", deadline("WEBHOOKS", queryTimeout, webhookController.RedeliverHandler)) // Повторная доставка
+
+	// Очистка журнала изменений от записей старше CHANGES_RETENTION
+	app.Go("song-changes-pruner", func(ctx context.Context) {
+		app.pruneChanges(ctx, cfg.ChangesRetention, cfg.ChangesPruneInterval)
+	})
+
+	// Доставка событий песен подписчикам вебхуков
+	app.Go("webhook-dispatcher", app.webhookService.Run)
+
+	// Рассылка изменений песен подписчикам /events
+	app.Go("change-stream", app.changeStream.Run)
 
 	// Запуск сервера
 	server := http.Server{
-		Addr:    ":" + appPort,
-		Handler: mux,
+		Addr: ":" + strconv.Itoa(cfg.AppPort),
+		Handler: middleware.Chain(logging.Routes(mux),
+			middleware.RequestID,
+			middleware.Tracing,
+			middleware.AccessLog(logger),
+			middleware.Metrics(app.metrics),
+			i18n.Middleware,
+			auth.Middleware,
+			middleware.Recover(logger),
+		),
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+	// Открытые потоки /events не заканчиваются сами: они завершаются в начале
+	// остановки, и клиенты переподключаются к другому экземпляру
+	server.RegisterOnShutdown(app.changeStream.Close)
+	logger.Info("сервер запущен", "port", cfg.AppPort)
+	if err := serve(ctx, &server, cfg, healthController, app); err != nil {
+		logger.Error("ошибка запуска сервера", "error", err)
+		exitCode = 1
+	}
+}
+
+// application содержит зависимости, общие для сервера и команд CLI.
+type application struct {
+	// ctx отменяется при остановке приложения; в нем работают фоновые задачи
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+	running atomic.Int32 // Количество работающих фоновых задач
+	closeDB sync.Once
+
+	db          *sql.DB
+	logger      *slog.Logger
+	metrics     *metrics.Metrics
+	songService *service.SongService
+	// webhookService управляет подписками и доставляет события из очереди
+	webhookService *service.WebhookService
+	// changeStream рассылает изменения песен подписчикам /events
+	changeStream *service.ChangeStream
+	// migrationVersion — последняя версия миграций в папке; 0 для хранилища в памяти
+	migrationVersion uint
+}
+
+// newApplication подключается к хранилищу, выполняет миграции и собирает сервисы.
+// DB_URL вида memory:// выбирает хранилище в памяти без внешней базы данных,
+// sqlite://путь — файл SQLite, остальные URL считаются адресами PostgreSQL.
+// ctx прерывает ожидание доступности базы данных при запуске.
+func newApplication(ctx context.Context, cfg *config.Config) *application {
+	dbURL := cfg.DBURL
+
+	// Логгер и метрики
+	logger := slog.Default()
+	appMetrics := metrics.New()
+
+	var db *sql.DB
+	var migrationVersion uint
+	var repo repository.Store
+	var detailsStore service.SongDetailsStore
+	var notifier service.ChangeNotifier
+	if isMemoryURL(dbURL) {
+		logger.Warn("используется хранилище в памяти: данные не сохраняются между запусками")
+		repo = repository.NewMemorySongStore(logger)
+	} else {
+		// Подключение к базе данных
+		var err error
+		db, err = openDatabase(ctx, cfg, logger)
+		if err != nil {
+			log.Fatal(err)
+		}
+		appMetrics.RegisterDB(db, databaseName(dbURL))
+
+		// Выполнение миграций через тот же пул соединений. Без автоматических
+		// миграций схему обновляет команда migrate, а до тех пор /readyz
+		// сообщает о несовпадении версии.
+		if cfg.DBAutoMigrate {
+			if err := migrations.RunMigrations(db, dbURL); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			logger.Warn("автоматические миграции отключены (DB_AUTO_MIGRATE=false)")
+		}
+		version, err := migrations.LatestVersion(dbURL)
+		if err != nil {
+			log.Fatalf("Ошибка чтения версии миграций: %v", err)
+		}
+		migrationVersion = version
+
+		if isSQLiteURL(dbURL) {
+			repo = repository.NewSQLiteSongRepository(db, logger)
+		} else {
+			repo = repository.NewSongRepository(db, logger)
+			notifier = repository.NewChangeListener(dbURL, logger)
+		}
+		if cfg.DetailsCachePersistent {
+			detailsStore = repository.NewSongDetailsCacheRepository(db, logger)
+		}
 	}
-	log.Printf("Сервер запущен на порту %s", appPort)
-	log.Fatal(server.ListenAndServe())
+
+	// Кэш ответов внешнего API
+	cacheConfig := service.SongDetailsCacheConfig{
+		Size:        cfg.DetailsCacheSize,
+		TTL:         cfg.DetailsCacheTTL,
+		NegativeTTL: cfg.DetailsCacheNegativeTTL,
+	}
+	detailsCache := service.NewSongDetailsCache(cacheConfig, detailsStore, logger)
+
+	// Сервис; запросы к внешнему API учитываются в метриках
+	client := &http.Client{Transport: appMetrics.Transport(nil)}
+	songService := service.NewSongService(repo, logger, cfg.APIBaseURL, client, detailsCache)
+	appMetrics.RegisterSongCounter(songService)
+
+	webhookService := service.NewWebhookService(repo, logger, nil, service.WebhookConfig{
+		Timeout:      cfg.WebhookTimeout,
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		RetryMin:     cfg.WebhookRetryMin,
+		RetryMax:     cfg.WebhookRetryMax,
+		PollInterval: cfg.WebhookPollInterval,
+		Retention:    cfg.WebhookRetention,
+	})
+
+	changeStream := service.NewChangeStream(repo, notifier, logger, cfg.EventsPollInterval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &application{
+		ctx:              ctx,
+		cancel:           cancel,
+		db:               db,
+		logger:           logger,
+		metrics:          appMetrics,
+		songService:      songService,
+		webhookService:   webhookService,
+		changeStream:     changeStream,
+		migrationVersion: migrationVersion,
+	}
+}
+
+// newLogger создает логгер приложения с уровнем levelName (по умолчанию info).
+func newLogger(levelName string) *slog.Logger {
+	level, err := logging.ParseLevel(levelName)
+	if err != nil {
+		log.Printf("Некорректная настройка LOG_LEVEL, используется info: %v", err)
+		level = slog.LevelInfo
+	}
+	return logging.New(os.Stdout, level)
+}
+
+// Go запускает фоновую задачу fn, которую остановка приложения дождется.
+// Контекст fn отменяется в начале остановки.
+func (app *application) Go(name string, fn func(ctx context.Context)) {
+	app.workers.Add(1)
+	app.running.Add(1)
+	go func() {
+		defer app.workers.Done()
+		defer app.running.Add(-1)
+		fn(app.ctx)
+		app.logger.Info("фоновая задача остановлена", "worker", name)
+	}()
+}
+
+// pruneChanges удаляет из журнала изменения старше retention сразу и затем
+// каждые interval, пока не отменен ctx.
+func (app *application) pruneChanges(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Ошибку уже записал сервис; следующая попытка — через interval
+		app.songService.PruneChanges(ctx, retention)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// StopWorkers останавливает фоновые задачи и ждет их завершения, но не
+// дольше срока ctx. Ресурсы приложения освобождает Close.
+func (app *application) StopWorkers(ctx context.Context) {
+	app.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		app.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if running := app.running.Load(); running > 0 {
+			app.logger.Warn("фоновые задачи не завершились за отведенное время", "running", running)
+		}
+	}
+}
+
+// Close освобождает ресурсы приложения. Повторные вызовы ничего не делают.
+func (app *application) Close() {
+	app.closeDB.Do(func() {
+		app.cancel()
+		if app.db == nil {
+			return
+		}
+		if err := app.db.Close(); err != nil {
+			app.logger.Error("ошибка закрытия соединения с базой данных", "error", err)
+		}
+	})
 }
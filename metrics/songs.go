@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"song-library/domain"
+)
+
+// songCountTimeout ограничивает время подсчета песен при сборе метрик.
+const songCountTimeout = 5 * time.Second
+
+// SongCounter подсчитывает песни в библиотеке.
+type SongCounter interface {
+	CountSongs(ctx context.Context) (domain.SongCounts, error)
+}
+
+// RegisterSongCounter добавляет показатели библиотеки: общее количество песен
+// и количество песен без деталей из внешнего API. Они подсчитываются при
+// каждом сборе метрик.
+func (m *Metrics) RegisterSongCounter(counter SongCounter) {
+	m.registry.MustRegister(&songCollector{
+		counter: counter,
+		total: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "songs"),
+			"Количество песен в библиотеке.", nil, nil),
+		pending: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "songs_pending_enrichment"),
+			"Количество песен, которым не хватает деталей из внешнего API.", nil, nil),
+	})
+}
+
+// songCollector собирает показатели библиотеки через SongCounter.
+type songCollector struct {
+	counter SongCounter
+	total   *prometheus.Desc
+	pending *prometheus.Desc
+}
+
+func (c *songCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.pending
+}
+
+func (c *songCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), songCountTimeout)
+	defer cancel()
+
+	counts, err := c.counter.CountSongs(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.total, err)
+		ch <- prometheus.NewInvalidMetric(c.pending, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(counts.Total))
+	ch <- prometheus.MustNewConstMetric(c.pending, prometheus.GaugeValue, float64(counts.PendingEnrichment))
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Результаты запроса к внешнему API.
+const (
+	outcomeOK       = "ok"        // Ответ 2xx
+	outcomeNotFound = "not_found" // Внешний API не знает песню
+	outcomeStatus   = "status"    // Ответ с другим статусом
+	outcomeTimeout  = "timeout"   // Истек срок запроса
+	outcomeCanceled = "canceled"  // Запрос отменен клиентом
+	outcomeNetwork  = "network"   // Ответ не получен
+)
+
+// Transport оборачивает next так, что каждый запрос к внешнему API
+// учитывается в метриках времени и ошибок. nil означает http.DefaultTransport.
+func (m *Metrics) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+
+		outcome := upstreamOutcome(resp, err)
+		m.upstreamDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+		if outcome != outcomeOK && outcome != outcomeNotFound {
+			m.upstreamErrors.WithLabelValues(outcome).Inc()
+		}
+		return resp, err
+	})
+}
+
+// upstreamOutcome определяет результат запроса по ответу и ошибке транспорта.
+func upstreamOutcome(resp *http.Response, err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return outcomeCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return outcomeTimeout
+	case err != nil:
+		return outcomeNetwork
+	case resp.StatusCode == http.StatusNotFound:
+		return outcomeNotFound
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return outcomeOK
+	default:
+		return outcomeStatus
+	}
+}
+
+// roundTripperFunc позволяет использовать функцию как http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,83 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"song-library/logging"
+	"song-library/middleware"
+)
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		level  string
+	}{
+		{name: "ok", status: http.StatusOK, body: `{"id":1}`, level: "INFO"},
+		{name: "not found", status: http.StatusNotFound, body: "missing", level: "INFO"},
+		{name: "server error", status: http.StatusInternalServerError, body: "", level: "ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := logging.New(&buf, slog.LevelDebug)
+			handler := middleware.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(5 * time.Millisecond)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}), middleware.RequestID, middleware.AccessLog(logger))
+
+			req := httptest.NewRequest(http.MethodGet, "/songs", nil)
+			req.Header.Set(middleware.RequestIDHeader, "access-log-test")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			var entry struct {
+				Level     string `json:"level"`
+				Path      string `json:"path"`
+				Status    int    `json:"status"`
+				Bytes     int64  `json:"bytes"`
+				Duration  int64  `json:"duration"`
+				RequestID string `json:"request_id"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("запись журнала %q: %v", buf.String(), err)
+			}
+			if entry.Level != tt.level || entry.Path != "/songs" || entry.Status != tt.status {
+				t.Errorf("запись журнала %+v, ожидались уровень %s и статус %d", entry, tt.level, tt.status)
+			}
+			if entry.Bytes != int64(len(tt.body)) {
+				t.Errorf("bytes = %d, ожидалось %d", entry.Bytes, len(tt.body))
+			}
+			if time.Duration(entry.Duration) < 5*time.Millisecond {
+				t.Errorf("duration = %v, ожидалось не меньше 5ms", time.Duration(entry.Duration))
+			}
+			if entry.RequestID != "access-log-test" {
+				t.Errorf("request_id = %q", entry.RequestID)
+			}
+		})
+	}
+}
+
+func TestAccessLogImplicitOK(t *testing.T) {
+	var buf bytes.Buffer
+	handler := middleware.AccessLog(logging.New(&buf, slog.LevelDebug))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/song/1", nil))
+
+	var entry struct {
+		Status int   `json:"status"`
+		Bytes  int64 `json:"bytes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("запись журнала %q: %v", buf.String(), err)
+	}
+	if entry.Status != http.StatusOK || entry.Bytes != 0 {
+		t.Errorf("запись журнала %+v, ожидался статус 200 без тела", entry)
+	}
+}
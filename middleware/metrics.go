@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"song-library/logging"
+	"song-library/metrics"
+)
+
+// unmatchedRoute — метка маршрута для запросов, не подошедших ни к одному
+// шаблону: так произвольные пути не порождают новые ряды метрик.
+const unmatchedRoute = "unmatched"
+
+// Metrics учитывает каждый запрос в метриках по шаблону маршрута и статусу
+// ответа. Шаблон берется из полей журнала запроса (см. logging.Routes),
+// поэтому обертка должна стоять после RequestID.
+func Metrics(m *metrics.Metrics) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := newResponseRecorder(w)
+			next.ServeHTTP(recorder, r)
+
+			route := logging.Route(r.Context())
+			if route == "" {
+				route = unmatchedRoute
+			}
+			m.ObserveRequest(route, recorder.statusOrOK(), time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Package middleware содержит обертки HTTP-обработчиков, общие для всех
+// маршрутов: идентификатор запроса, журнал доступа, перехват паник и
+// ограничение времени обработки.
+package middleware
+
+import "net/http"
+
+// Middleware оборачивает обработчик дополнительной логикой.
+type Middleware func(http.Handler) http.Handler
+
+// Chain оборачивает handler в middlewares так, что первая из них выполняется
+// первой: Chain(h, a, b) равносильно a(b(h)).
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// responseRecorder запоминает статус и размер ответа.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap открывает исходный ResponseWriter для http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// written сообщает, что заголовки ответа уже отправлены.
+func (r *responseRecorder) written() bool {
+	return r.status != 0
+}
+
+// statusOrOK возвращает статус ответа; обработчик, который ничего не
+// записал, отвечает 200.
+func (r *responseRecorder) statusOrOK() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"song-library/problem"
+)
+
+// Recover перехватывает панику обработчика, записывает ее в журнал со стеком
+// вызовов и отвечает 500 в формате application/problem+json, если ответ еще
+// не начат. Паника http.ErrAbortHandler пробрасывается дальше: ею обработчик
+// намеренно обрывает соединение.
+func Recover(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := newResponseRecorder(w)
+			defer func() {
+				value := recover()
+				if value == nil {
+					return
+				}
+				if err, ok := value.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(value)
+				}
+
+				logger.ErrorContext(r.Context(), "паника при обработке запроса",
+					"panic", fmt.Sprint(value), "stack", string(debug.Stack()))
+				if recorder.written() {
+					// Заголовки уже отправлены: остается только оборвать ответ
+					panic(http.ErrAbortHandler)
+				}
+				problem.Write(recorder, r, "op.handle_request", fmt.Errorf("паника: %v", value))
+			}()
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"song-library/domain"
+	"song-library/logging"
+	"song-library/middleware"
+	"song-library/problem"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	var buf bytes.Buffer
+	handler := middleware.Recover(logging.New(&buf, slog.LevelDebug))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("сломалось")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/songs", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("статус %d, ожидался 500", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/problem+json") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var got problem.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("тело ответа %q: %v", rec.Body.String(), err)
+	}
+	if got.Status != http.StatusInternalServerError || got.Code != domain.CodeInternal {
+		t.Errorf("problem = %+v", got)
+	}
+	if !strings.Contains(buf.String(), "сломалось") || !strings.Contains(buf.String(), `"stack"`) {
+		t.Errorf("паника не записана в журнал со стеком: %s", buf.String())
+	}
+}
+
+func TestRecoverAfterWrite(t *testing.T) {
+	handler := middleware.Recover(slog.New(slog.NewTextHandler(io.Discard, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("сломалось")
+	}))
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, http.ErrAbortHandler) {
+			t.Errorf("паника %v, ожидалась http.ErrAbortHandler", err)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/songs", nil))
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"song-library/logging"
+)
+
+// RequestIDHeader — заголовок с идентификатором запроса.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ограничивает длину идентификатора, принятого от клиента.
+const maxRequestIDLength = 128
+
+// RequestID берет идентификатор запроса из заголовка X-Request-ID или создает
+// новый, возвращает его в том же заголовке ответа и заводит поля журнала
+// запроса (см. logging.WithRequest).
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = logging.NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequest(r.Context(), requestID, r)))
+	})
+}
+
+// validRequestID принимает непустые идентификаторы из видимых символов ASCII,
+// чтобы чужое значение не испортило журнал и заголовки ответа.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"song-library/logging"
+	"song-library/middleware"
+)
+
+func TestRequestIDGenerated(t *testing.T) {
+	var seen string
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/songs", nil))
+
+	id := rec.Header().Get(middleware.RequestIDHeader)
+	if id == "" {
+		t.Fatal("заголовок X-Request-ID не установлен")
+	}
+	if seen != id {
+		t.Errorf("идентификатор в контексте %q, в ответе %q", seen, id)
+	}
+}
+
+func TestRequestIDPropagated(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "valid", header: "client-id-42", keep: true},
+		{name: "control characters", header: "bad\tid", keep: false},
+		{name: "too long", header: strings.Repeat("a", 129), keep: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/songs", nil)
+			req.Header.Set(middleware.RequestIDHeader, tt.header)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(middleware.RequestIDHeader)
+			if (id == tt.header) != tt.keep || id == "" {
+				t.Errorf("X-Request-ID в ответе %q для %q, сохранение ожидалось: %v", id, tt.header, tt.keep)
+			}
+			if seen != id {
+				t.Errorf("идентификатор в контексте %q, в ответе %q", seen, id)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"song-library/problem"
+)
+
+// Timeout ограничивает время обработки запроса с помощью http.TimeoutHandler:
+// контекст запроса, который передается в сервис и репозиторий, отменяется
+// через timeout, и если обработчик не ответил к этому сроку, клиент получает
+// ошибку timeout в формате application/problem+json. Нулевой timeout
+// оставляет только отмену при отключении клиента.
+//
+// Ответ обработчика под ограничением буферизуется целиком, поэтому потоковые
+// маршруты (импорт и экспорт) по умолчанию не ограничиваются.
+func Timeout(timeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			// Текст ответа зависит от языка запроса, поэтому обработчик
+			// с ограничением собирается для каждого запроса
+			failure := problem.New(r, "op.handle_request", context.DeadlineExceeded)
+			body, _ := json.Marshal(failure)
+			writer := &timeoutWriter{ResponseWriter: w, ctx: ctx, status: failure.Status}
+			http.TimeoutHandler(next, timeout, string(body)).ServeHTTP(writer, r)
+		})
+	}
+}
+
+// timeoutWriter превращает ответ 503, которым http.TimeoutHandler сообщает
+// об истечении срока, в ошибку timeout из каталога с ее статусом.
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx    context.Context
+	status int
+}
+
+func (w *timeoutWriter) WriteHeader(status int) {
+	header := w.Header()
+	if status == http.StatusServiceUnavailable && header.Get("Content-Type") == "" &&
+		errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		problem.SetHeaders(header)
+		status = w.status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap открывает исходный ResponseWriter для http.ResponseController.
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
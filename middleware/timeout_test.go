@@ -0,0 +1,71 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"song-library/domain"
+	"song-library/middleware"
+	"song-library/problem"
+)
+
+func TestTimeoutCutsOffSlowHandler(t *testing.T) {
+	canceled := make(chan struct{})
+	handler := middleware.Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(canceled)
+		case <-time.After(time.Second):
+			w.Write([]byte("слишком поздно"))
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/songs", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("статус %d, ожидался 504", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/problem+json") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var got problem.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("тело ответа %q: %v", rec.Body.String(), err)
+	}
+	if got.Code != domain.CodeTimeout {
+		t.Errorf("problem = %+v", got)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("контекст запроса не отменен")
+	}
+}
+
+func TestTimeoutFastHandler(t *testing.T) {
+	handler := middleware.Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/song", nil))
+
+	if rec.Code != http.StatusCreated || rec.Body.String() != `{"id":1}` {
+		t.Errorf("ответ %d %q, ожидался 201 от обработчика", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTimeoutDisabled(t *testing.T) {
+	handler := middleware.Timeout(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("нулевой timeout установил срок контекста")
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/songs", nil))
+}
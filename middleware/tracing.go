@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"song-library/logging"
+)
+
+var tracer = otel.Tracer("song-library/http")
+
+// Tracing открывает серверный спан OpenTelemetry для каждого запроса,
+// продолжая трассировку из заголовка traceparent, если он есть. Спан
+// называется по шаблону маршрута из полей журнала запроса, поэтому обертка
+// должна стоять после RequestID. Ответы 5xx отмечают спан ошибкой.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path)),
+		)
+		defer span.End()
+
+		recorder := newResponseRecorder(w)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		if route := logging.Route(ctx); route != "" {
+			span.SetName(route)
+			_, path, _ := strings.Cut(route, " ")
+			span.SetAttributes(semconv.HTTPRoute(path))
+		}
+		status := recorder.statusOrOK()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
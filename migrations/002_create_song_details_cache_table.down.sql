@@ -0,0 +1,2 @@
+-- Откат: удаление постоянного кэша деталей песен
+DROP TABLE IF EXISTS song_details_cache;
@@ -0,0 +1,29 @@
+-- Уникальность пары группа/название нужна для пропуска дубликатов при импорте.
+-- После этой миграции POST /song и PUT /song/{id} отвечают 409 (song_exists)
+-- на повтор пары. Раньше дубликаты допускались; если они есть, миграция
+-- останавливается и перечисляет их, а не удаляет песни молча: лишние записи
+-- нужно удалить вручную, выполнить migrate force 2 и повторить migrate up.
+DO $$
+DECLARE
+    total integer;
+    listed text;
+BEGIN
+    SELECT COUNT(*), string_agg(format('%s — %s (ID %s)', group_name, song_name, ids), '; ')
+        FILTER (WHERE n <= 20)
+    INTO total, listed
+    FROM (
+        SELECT group_name, song_name, string_agg(id::text, ', ' ORDER BY id) AS ids,
+            row_number() OVER (ORDER BY MIN(id)) AS n
+        FROM songs
+        GROUP BY group_name, song_name
+        HAVING COUNT(*) > 1
+    ) duplicates;
+
+    IF total > 0 THEN
+        RAISE EXCEPTION 'в таблице songs % повторяющихся пар группа/название (первые 20): %', total, listed
+            USING HINT = 'Удалите лишние песни, выполните migrate force 2 и повторите migrate up';
+    END IF;
+END
+$$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS songs_group_name_song_name_idx ON songs (group_name, song_name);
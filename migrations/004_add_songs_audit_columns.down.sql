@@ -0,0 +1,11 @@
+-- Откат: удаление триггера времени обновления и колонок авторов изменений
+DROP INDEX IF EXISTS songs_updated_at_idx;
+DROP TRIGGER IF EXISTS songs_set_updated_at ON songs;
+DROP FUNCTION IF EXISTS songs_set_updated_at();
+ALTER TABLE songs
+    DROP COLUMN IF EXISTS created_by,
+    DROP COLUMN IF EXISTS updated_by,
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN updated_at DROP NOT NULL,
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN updated_at TYPE TIMESTAMP;
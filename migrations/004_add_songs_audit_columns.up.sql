@@ -0,0 +1,25 @@
+-- Время изменения поддерживает триггер, автор изменения приходит из приложения
+ALTER TABLE songs ALTER COLUMN created_at TYPE TIMESTAMPTZ, ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+UPDATE songs SET created_at = COALESCE(created_at, now()), updated_at = COALESCE(updated_at, created_at, now())
+    WHERE created_at IS NULL OR updated_at IS NULL;
+ALTER TABLE songs
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at SET NOT NULL,
+    ADD COLUMN created_by TEXT NOT NULL DEFAULT '', -- Пользователь, добавивший песню
+    ADD COLUMN updated_by TEXT NOT NULL DEFAULT ''; -- Пользователь, последним изменивший песню
+
+CREATE OR REPLACE FUNCTION songs_set_updated_at() RETURNS trigger AS $$
+BEGIN
+    -- Запись без изменений не сдвигает время обновления
+    IF NEW IS DISTINCT FROM OLD THEN
+        NEW.updated_at = now();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER songs_set_updated_at BEFORE UPDATE ON songs
+    FOR EACH ROW EXECUTE FUNCTION songs_set_updated_at();
+
+-- Выборка изменений для инкрементальной синхронизации (updated_since)
+CREATE INDEX songs_updated_at_idx ON songs (updated_at);
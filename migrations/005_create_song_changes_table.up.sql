@@ -0,0 +1,20 @@
+-- Журнал изменений песен для инкрементальной синхронизации (GET /changes).
+-- Записи добавляет репозиторий в транзакции изменения песни.
+CREATE TABLE IF NOT EXISTS song_changes (
+    id BIGSERIAL PRIMARY KEY,                     -- Номер изменения; номера растут в порядке фиксации
+    song_id INTEGER NOT NULL,                     -- ID измененной песни
+    operation TEXT NOT NULL,                      -- created, updated или deleted
+    song JSONB,                                   -- Снимок песни после изменения; NULL для удаления
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now() -- Время изменения
+);
+CREATE INDEX IF NOT EXISTS song_changes_changed_at_idx ON song_changes (changed_at);
+
+-- Состояние журнала из одной строки. log_id входит в токены синхронизации,
+-- чтобы токены другой базы (например, до восстановления из архива) считались
+-- устаревшими; pruned_through — наибольший номер изменения, удаленного по
+-- сроку хранения.
+CREATE TABLE IF NOT EXISTS song_changes_state (
+    log_id TEXT NOT NULL,
+    pruned_through BIGINT NOT NULL DEFAULT 0
+);
+INSERT INTO song_changes_state (log_id) VALUES (substr(md5(random()::text || clock_timestamp()::text), 1, 16));
@@ -0,0 +1,3 @@
+-- Откат: удаление вебхуков и журнала доставок
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,29 @@
+-- Подписки на события песен (вебхуки)
+CREATE TABLE IF NOT EXISTS webhooks (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,                             -- Адрес получателя
+    events TEXT NOT NULL,                          -- События через запятую, например song.created,song.deleted
+    secret TEXT NOT NULL,                          -- Ключ подписи HMAC-SHA256
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(), -- Время создания подписки
+    created_by TEXT NOT NULL DEFAULT ''            -- Пользователь, создавший подписку
+);
+
+-- Очередь и журнал доставок. Записи добавляет репозиторий в транзакции
+-- изменения песни, отправляет фоновая задача.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event TEXT NOT NULL,                                -- Событие, например song.created
+    payload TEXT NOT NULL,                              -- Тело запроса; подписываются именно эти байты
+    status TEXT NOT NULL DEFAULT 'pending',             -- pending, delivered или failed
+    attempts INTEGER NOT NULL DEFAULT 0,                -- Количество выполненных попыток
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(), -- Время следующей попытки для pending
+    last_attempt_at TIMESTAMPTZ,                        -- Время последней попытки
+    response_status INTEGER NOT NULL DEFAULT 0,         -- HTTP-статус последнего ответа, 0 — ответа не было
+    last_error TEXT NOT NULL DEFAULT '',                -- Ошибка последней попытки
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),      -- Время события
+    delivered_at TIMESTAMPTZ                            -- Время успешной доставки
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_created_at_idx ON webhook_deliveries (created_at);
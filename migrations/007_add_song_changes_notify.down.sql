@@ -0,0 +1,4 @@
+-- Откат: удаление уведомлений об изменении песен и группы в журнале изменений
+DROP TRIGGER IF EXISTS songs_notify_changes ON songs;
+DROP FUNCTION IF EXISTS songs_notify_changes();
+ALTER TABLE song_changes DROP COLUMN IF EXISTS group_name;
@@ -0,0 +1,17 @@
+-- Группа песни в журнале изменений: по ней поток событий (GET /events)
+-- фильтрует изменения, в том числе удаления, для которых снимка песни нет.
+ALTER TABLE song_changes ADD COLUMN group_name TEXT NOT NULL DEFAULT '';
+UPDATE song_changes SET group_name = song->>'group' WHERE song IS NOT NULL;
+
+-- Уведомление слушателей канала song_changes об изменении песен. NOTIFY
+-- доставляется после фиксации транзакции, когда записи журнала изменений
+-- уже видны; одинаковые уведомления одной транзакции PostgreSQL объединяет.
+CREATE OR REPLACE FUNCTION songs_notify_changes() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('song_changes', lower(TG_OP));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER songs_notify_changes AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON songs
+    FOR EACH STATEMENT EXECUTE FUNCTION songs_notify_changes();
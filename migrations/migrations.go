@@ -1,27 +1,235 @@
+// Package migrations содержит миграции схемы базы данных и функции для их
+// применения. Файлы миграций встроены в исполняемый файл, поэтому приложение
+// не зависит от рабочего каталога.
 package migrations
 
 import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
-	"log"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
+	"log/slog"
+	"strings"
+)
+
+// files содержит миграции для PostgreSQL (в корне) и SQLite (в папке sqlite).
+// Номера версий в обеих папках совпадают, чтобы версия схемы означала одно и то же.
+//
+//go:embed *.sql sqlite/*.sql
+var files embed.FS
 
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+// Папки с миграциями внутри files.
+const (
+	postgresDir = "."
+	sqliteDir   = "sqlite"
 )
 
-func RunMigrations(dbURL string) {
-	m, err := migrate.New(
-		"file://migrations", // Путь к папке с миграциями
-		dbURL,               // URL подключения к базе данных
-	)
+// newSource открывает миграции для СУБД, на которую указывает dbURL.
+func newSource(dbURL string) (source.Driver, error) {
+	dir := postgresDir
+	if strings.HasPrefix(dbURL, "sqlite://") {
+		dir = sqliteDir
+	}
+	driver, err := iofs.New(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия источника миграций: %w", err)
+	}
+	return driver, nil
+}
+
+// RunMigrations применяет к базе данных все новые миграции. Миграции
+// выполняются через открытый пул db, отдельное соединение по dbURL не
+// открывается; dbURL нужен только для выбора набора миграций.
+func RunMigrations(db *sql.DB, dbURL string) error {
+	m, err := newMigrate(db, dbURL)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("ошибка выполнения миграции: %w", err)
+	}
+
+	slog.Info("миграции успешно применены")
+	return nil
+}
+
+// Steps применяет n следующих миграций или, при отрицательном n, откатывает
+// -n последних.
+func Steps(db *sql.DB, dbURL string, n int) error {
+	m, err := newMigrate(db, dbURL)
 	if err != nil {
-		log.Fatalf("Ошибка инициализации миграции: %v", err)
+		return err
 	}
+	defer m.Close()
 
-	err = m.Up()
-	if err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("Ошибка выполнения миграции: %v", err)
+	var short migrate.ErrShortLimit
+	switch err := m.Steps(n); {
+	case err == nil, errors.Is(err, migrate.ErrNoChange):
+		return nil
+	case errors.As(err, &short):
+		return fmt.Errorf("выполнена лишь часть миграций: не хватило %d из %d запрошенных", short.Short, abs(n))
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("нет миграций для выполнения %d шагов", n)
+	default:
+		return fmt.Errorf("ошибка выполнения миграции: %w", err)
+	}
+}
+
+// RollbackAll откатывает все примененные миграции.
+func RollbackAll(db *sql.DB, dbURL string) error {
+	m, err := newMigrate(db, dbURL)
+	if err != nil {
+		return err
 	}
+	defer m.Close()
 
-	fmt.Println("Миграции успешно применены")
+	if err := m.Down(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("ошибка отката миграций: %w", err)
+	}
+	return nil
+}
+
+// Force записывает версию схемы version и снимает признак dirty, не выполняя
+// миграций. Нужна после ручного исправления схемы, когда миграция завершилась
+// с ошибкой. Версия -1 означает схему без примененных миграций.
+func Force(db *sql.DB, dbURL string, version int) error {
+	m, err := newMigrate(db, dbURL)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Force(version); err != nil {
+		return fmt.Errorf("ошибка установки версии схемы %d: %w", version, err)
+	}
+	return nil
+}
+
+// newMigrate создает migrate.Migrate поверх открытого пула db.
+func newMigrate(db *sql.DB, dbURL string) (*migrate.Migrate, error) {
+	var driver database.Driver
+	var err error
+	name := "postgres"
+	if strings.HasPrefix(dbURL, "sqlite://") {
+		name = "sqlite"
+		driver, err = sqlite.WithInstance(db, &sqlite.Config{})
+	} else {
+		driver, err = postgres.WithInstance(db, &postgres.Config{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка инициализации миграции: %w", err)
+	}
+	if name == "sqlite" {
+		// Драйвер SQLite закрывает переданный пул вместе с собой
+		driver = sharedDriver{driver}
+	}
+
+	src, err := newSource(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	m, err := migrate.NewWithInstance("iofs", src, name, driver)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка инициализации миграции: %w", err)
+	}
+	m.Log = logger{}
+	return m, nil
+}
+
+// sharedDriver не дает migrate закрыть пул соединений, который
+// продолжает использовать приложение.
+type sharedDriver struct {
+	database.Driver
+}
+
+func (sharedDriver) Close() error { return nil }
+
+// logger передает в журнал сообщения migrate о выполненных миграциях.
+type logger struct{}
+
+func (logger) Printf(format string, args ...any) {
+	slog.Info(strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
+
+func (logger) Verbose() bool { return false }
+
+// LatestVersion возвращает номер последней миграции для базы dbURL, известной этой сборке.
+func LatestVersion(dbURL string) (uint, error) {
+	driver, err := newSource(dbURL)
+	if err != nil {
+		return 0, err
+	}
+	defer driver.Close()
+
+	version, err := driver.First()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения первой миграции: %w", err)
+	}
+	for {
+		next, err := driver.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("ошибка чтения миграции после %d: %w", version, err)
+		}
+		version = next
+	}
+}
+
+// SchemaVersion возвращает текущую версию схемы базы данных.
+// Для базы без примененных миграций возвращается 0.
+func SchemaVersion(db *sql.DB, dbURL string) (version uint, dirty bool, err error) {
+	m, err := newMigrate(db, dbURL)
+	if err != nil {
+		return 0, false, err
+	}
+	defer m.Close()
+
+	version, dirty, err = m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// MigrateTo переводит схему базы данных на указанную версию.
+func MigrateTo(db *sql.DB, dbURL string, version uint) error {
+	m, err := newMigrate(db, dbURL)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Migrate(version); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("ошибка миграции до версии %d: %w", version, err)
+	}
+	return nil
+}
+
+// CurrentVersion читает версию схемы из таблицы schema_migrations через
+// открытое соединение db. Для базы без примененных миграций возвращается 0.
+func CurrentVersion(ctx context.Context, db *sql.DB) (version uint, dirty bool, err error) {
+	err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
 }
@@ -0,0 +1,2 @@
+-- Откат: удаление таблицы песен
+DROP TABLE IF EXISTS songs;
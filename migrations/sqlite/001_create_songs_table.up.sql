@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS songs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,          -- Уникальный идентификатор
+    group_name TEXT NOT NULL,                      -- Название группы
+    song_name TEXT NOT NULL,                       -- Название песни
+    release_date TEXT NOT NULL,                    -- Дата релиза
+    text TEXT NOT NULL,                            -- Текст песни
+    link TEXT NOT NULL,                            -- Ссылка на дополнительную информацию
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Дата создания записи
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP  -- Дата последнего обновления записи
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS song_details_cache (
+    cache_key TEXT PRIMARY KEY,          -- Нормализованный ключ (группа и название песни)
+    release_date TEXT NOT NULL,          -- Дата релиза
+    text TEXT NOT NULL,                  -- Текст песни
+    link TEXT NOT NULL,                  -- Ссылка на дополнительную информацию
+    not_found BOOLEAN NOT NULL,          -- Внешний API ответил, что песня не найдена
+    expires_at TIMESTAMP NOT NULL        -- Время устаревания записи
+);
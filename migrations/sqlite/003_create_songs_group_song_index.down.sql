@@ -0,0 +1,2 @@
+-- Откат: удаление уникального индекса группа/название
+DROP INDEX IF EXISTS songs_group_name_song_name_idx;
@@ -0,0 +1,27 @@
+-- Уникальность пары группа/название нужна для пропуска дубликатов при импорте.
+-- После этой миграции POST /song и PUT /song/{id} отвечают 409 (song_exists)
+-- на повтор пары. Раньше дубликаты допускались; если они есть, миграция
+-- останавливается и перечисляет их, а не удаляет песни молча: лишние записи
+-- нужно удалить вручную, выполнить migrate force 2 и повторить migrate up.
+-- RAISE доступен только в триггере, поэтому ошибка поднимается триггером
+-- временной таблицы, в которую попадает список повторов, если он не пуст.
+CREATE TEMP TABLE IF NOT EXISTS songs_duplicates_check (message TEXT);
+CREATE TEMP TRIGGER IF NOT EXISTS songs_duplicates_abort BEFORE INSERT ON songs_duplicates_check
+BEGIN
+    SELECT RAISE(ABORT, NEW.message);
+END;
+INSERT INTO songs_duplicates_check
+    SELECT 'в таблице songs ' || COUNT(*) || ' повторяющихся пар группа/название (первые 20): '
+        || group_concat(CASE WHEN n <= 20 THEN group_name || ' — ' || song_name || ' (ID ' || ids || ')' END, '; ')
+        || '. Удалите лишние песни, выполните migrate force 2 и повторите migrate up'
+    FROM (
+        SELECT group_name, song_name, group_concat(id, ', ') AS ids,
+            row_number() OVER (ORDER BY MIN(id)) AS n
+        FROM (SELECT id, group_name, song_name FROM songs ORDER BY id)
+        GROUP BY group_name, song_name
+        HAVING COUNT(*) > 1
+    )
+    HAVING COUNT(*) > 0;
+DROP TABLE songs_duplicates_check;
+
+CREATE UNIQUE INDEX IF NOT EXISTS songs_group_name_song_name_idx ON songs (group_name, song_name);
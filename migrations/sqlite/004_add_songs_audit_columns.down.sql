@@ -0,0 +1,5 @@
+-- Откат: удаление триггера времени обновления и колонок авторов изменений
+DROP INDEX IF EXISTS songs_updated_at_idx;
+DROP TRIGGER IF EXISTS songs_set_updated_at;
+ALTER TABLE songs DROP COLUMN created_by;
+ALTER TABLE songs DROP COLUMN updated_by;
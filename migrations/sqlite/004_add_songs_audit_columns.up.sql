@@ -0,0 +1,21 @@
+-- Время изменения поддерживает триггер, автор изменения приходит из приложения
+ALTER TABLE songs ADD COLUMN created_by TEXT NOT NULL DEFAULT ''; -- Пользователь, добавивший песню
+ALTER TABLE songs ADD COLUMN updated_by TEXT NOT NULL DEFAULT ''; -- Пользователь, последним изменивший песню
+UPDATE songs SET created_at = COALESCE(created_at, CURRENT_TIMESTAMP), updated_at = COALESCE(updated_at, created_at, CURRENT_TIMESTAMP)
+    WHERE created_at IS NULL OR updated_at IS NULL;
+
+-- SQLite не меняет NEW в триггере, поэтому время записывается отдельным
+-- UPDATE; рекурсивные триггеры по умолчанию выключены. Запись без изменений
+-- не сдвигает время обновления.
+CREATE TRIGGER songs_set_updated_at AFTER UPDATE ON songs
+    FOR EACH ROW WHEN NEW.updated_at IS OLD.updated_at AND (
+        NEW.group_name IS NOT OLD.group_name OR NEW.song_name IS NOT OLD.song_name
+        OR NEW.release_date IS NOT OLD.release_date OR NEW.text IS NOT OLD.text
+        OR NEW.link IS NOT OLD.link OR NEW.updated_by IS NOT OLD.updated_by
+    )
+BEGIN
+    UPDATE songs SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
+
+-- Выборка изменений для инкрементальной синхронизации (updated_since)
+CREATE INDEX songs_updated_at_idx ON songs (updated_at);
@@ -0,0 +1,3 @@
+-- Откат: удаление журнала изменений песен
+DROP TABLE IF EXISTS song_changes_state;
+DROP TABLE IF EXISTS song_changes;
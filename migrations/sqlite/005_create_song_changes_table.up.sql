@@ -0,0 +1,20 @@
+-- Журнал изменений песен для инкрементальной синхронизации (GET /changes).
+-- Записи добавляет репозиторий в транзакции изменения песни. AUTOINCREMENT
+-- не дает повторно использовать номера после удаления старых записей.
+CREATE TABLE IF NOT EXISTS song_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,         -- Номер изменения; номера растут в порядке фиксации
+    song_id INTEGER NOT NULL,                     -- ID измененной песни
+    operation TEXT NOT NULL,                      -- created, updated или deleted
+    song TEXT,                                    -- Снимок песни после изменения (JSON); NULL для удаления
+    changed_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')) -- Время изменения
+);
+CREATE INDEX IF NOT EXISTS song_changes_changed_at_idx ON song_changes (changed_at);
+
+-- Состояние журнала из одной строки. log_id входит в токены синхронизации,
+-- чтобы токены другой базы считались устаревшими; pruned_through —
+-- наибольший номер изменения, удаленного по сроку хранения.
+CREATE TABLE IF NOT EXISTS song_changes_state (
+    log_id TEXT NOT NULL,
+    pruned_through INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO song_changes_state (log_id) VALUES (lower(hex(randomblob(8))));
@@ -0,0 +1,2 @@
+-- Откат: удаление группы в журнале изменений
+ALTER TABLE song_changes DROP COLUMN group_name;
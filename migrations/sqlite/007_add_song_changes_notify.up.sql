@@ -0,0 +1,5 @@
+-- Группа песни в журнале изменений: по ней поток событий (GET /events)
+-- фильтрует изменения, в том числе удаления, для которых снимка песни нет.
+-- Уведомлений в SQLite нет: поток событий периодически читает журнал.
+ALTER TABLE song_changes ADD COLUMN group_name TEXT NOT NULL DEFAULT '';
+UPDATE song_changes SET group_name = json_extract(song, '$.group') WHERE song IS NOT NULL;
@@ -0,0 +1,132 @@
+// Package problem описывает ответы об ошибках в формате RFC 7807
+// (application/problem+json). Его используют и контроллеры, и промежуточные
+// обработчики, которые отвечают клиенту сами (Recover, Timeout).
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"song-library/domain"
+	"song-library/i18n"
+)
+
+// ContentType — тип содержимого ответов об ошибках по RFC 7807.
+const ContentType = "application/problem+json"
+
+// typeBase — префикс URI типа ошибки; по нему же отдается описание кода.
+const typeBase = "/errors/"
+
+// Problem описывает ошибку в формате RFC 7807 (application/problem+json).
+type Problem struct {
+	Type     string              `json:"type" example:"/errors/song_not_found"`
+	Title    string              `json:"title" example:"Песня не найдена"`
+	Status   int                 `json:"status" example:"404"`
+	Detail   string              `json:"detail,omitempty" example:"Ошибка удаления песни: песня не найдена"`
+	Instance string              `json:"instance,omitempty" example:"/song/42"`
+	Code     domain.ErrorCode    `json:"code" example:"song_not_found"`
+	Errors   []domain.FieldError `json:"errors,omitempty"`
+}
+
+// CodeInfo описывает код ошибки из каталога.
+type CodeInfo struct {
+	Code   domain.ErrorCode `json:"code" example:"song_not_found"`
+	Type   string           `json:"type" example:"/errors/song_not_found"`
+	Status int              `json:"status" example:"404"`
+	Title  string           `json:"title" example:"Песня не найдена"`
+}
+
+// statuses сопоставляет кодам ошибок HTTP-статусы. Заголовки ошибок
+// хранятся в каталогах сообщений i18n под ключами title.<код>.
+var statuses = map[domain.ErrorCode]int{
+	domain.CodeValidationFailed:     http.StatusBadRequest,
+	domain.CodeMalformedBody:        http.StatusBadRequest,
+	domain.CodeUnsupportedFormat:    http.StatusBadRequest,
+	domain.CodeSongNotFound:         http.StatusNotFound,
+	domain.CodeVersesNotFound:       http.StatusNotFound,
+	domain.CodeUpstreamSongNotFound: http.StatusNotFound,
+	domain.CodeSongExists:           http.StatusConflict,
+	domain.CodeSyncTokenExpired:     http.StatusGone,
+	domain.CodeWebhookNotFound:      http.StatusNotFound,
+	domain.CodeDeliveryNotFound:     http.StatusNotFound,
+	domain.CodeConflict:             http.StatusConflict,
+	domain.CodeNotFound:             http.StatusNotFound,
+	domain.CodeUpstreamUnavailable:  http.StatusBadGateway,
+	domain.CodeTimeout:              http.StatusGatewayTimeout,
+	domain.CodeInternal:             http.StatusInternalServerError,
+}
+
+// Lookup возвращает описание кода на языке lang.
+func Lookup(code domain.ErrorCode, lang i18n.Language) (CodeInfo, bool) {
+	status, ok := statuses[code]
+	if !ok {
+		return CodeInfo{}, false
+	}
+	return CodeInfo{
+		Code:   code,
+		Type:   typeBase + string(code),
+		Status: status,
+		Title:  i18n.T(lang, "title."+string(code)),
+	}, true
+}
+
+// Write отправляет ошибку err в формате application/problem+json с
+// HTTP-статусом, выбранным по ее коду (см. New).
+func Write(w http.ResponseWriter, r *http.Request, operation string, err error) {
+	problem := New(r, operation, err)
+	SetHeaders(w.Header())
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// New описывает ошибку err, возникшую при обработке запроса r.
+// operation — ключ сообщения об операции (op.*), которое предшествует
+// описанию ошибки в поле detail. Тексты берутся из каталога языка,
+// выбранного по Accept-Language.
+func New(r *http.Request, operation string, err error) Problem {
+	lang := i18n.FromContext(r.Context())
+	code := codeFromError(r, err)
+	info, _ := Lookup(code, lang)
+
+	detail := i18n.T(lang, "error."+string(code))
+	var validation *domain.ValidationError
+	if errors.As(err, &validation) {
+		detail = domain.LocalizeError(lang, err)
+	}
+
+	problem := Problem{
+		Type:     info.Type,
+		Title:    info.Title,
+		Status:   info.Status,
+		Detail:   i18n.T(lang, operation) + ": " + detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+	if validation != nil {
+		problem.Errors = validation.Localize(lang)
+	}
+	return problem
+}
+
+// SetHeaders задает заголовки ответа application/problem+json.
+func SetHeaders(header http.Header) {
+	header.Set("Content-Type", ContentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+}
+
+// codeFromError выбирает код ошибки из каталога. Ошибки без известного
+// кода считаются внутренними.
+func codeFromError(r *http.Request, err error) domain.ErrorCode {
+	code := domain.CodeOf(err)
+	if code != domain.CodeInternal && code != domain.CodeUpstreamUnavailable {
+		return code
+	}
+	// Драйвер базы данных не всегда оборачивает ошибку контекста,
+	// поэтому истечение срока проверяется и по контексту запроса.
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		return domain.CodeTimeout
+	}
+	return code
+}
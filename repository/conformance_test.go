@@ -0,0 +1,513 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"song-library/domain"
+	"song-library/migrations"
+	"song-library/repository"
+)
+
+// discardLogger — логгер хранилищ в тестах.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestMemorySongStore(t *testing.T) {
+	testSongStore(t, func(t *testing.T) repository.Store {
+		return repository.NewMemorySongStore(discardLogger)
+	})
+}
+
+func TestSQLiteSongStore(t *testing.T) {
+	testSongStore(t, func(t *testing.T) repository.Store {
+		path := filepath.Join(t.TempDir(), "songs.db")
+		// Параметры совпадают с теми, что приложение добавляет к sqlite:// в DB_URL
+		db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_time_format=sqlite")
+		if err != nil {
+			t.Fatalf("открытие SQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if err := migrations.RunMigrations(db, "sqlite://"+path); err != nil {
+			t.Fatalf("миграции SQLite: %v", err)
+		}
+		return repository.NewSQLiteSongRepository(db, discardLogger)
+	})
+}
+
+// TestPostgresSongStore запускается, только если в TEST_DB_URL указана
+// тестовая база PostgreSQL: ее таблицы очищаются перед каждым тестом.
+func TestPostgresSongStore(t *testing.T) {
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL не задан")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("подключение к PostgreSQL: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migrations.RunMigrations(db, dbURL); err != nil {
+		t.Fatalf("миграции PostgreSQL: %v", err)
+	}
+
+	testSongStore(t, func(t *testing.T) repository.Store {
+		_, err := db.Exec("TRUNCATE songs, song_changes, webhooks, webhook_deliveries, song_details_cache RESTART IDENTITY CASCADE")
+		if err == nil {
+			_, err = db.Exec("UPDATE song_changes_state SET pruned_through = 0")
+		}
+		if err != nil {
+			t.Fatalf("очистка таблиц: %v", err)
+		}
+		return repository.NewSongRepository(db, discardLogger)
+	})
+}
+
+// testSongStore проверяет, что хранилище, созданное newStore, ведет себя
+// так, как описывают SongStore и WebhookStore. newStore вызывается для каждого подтеста и
+// должен возвращать пустое хранилище.
+func testSongStore(t *testing.T, newStore func(t *testing.T) repository.Store) {
+	ctx := context.Background()
+
+	t.Run("AddAndGet", func(t *testing.T) {
+		store := newStore(t)
+		id := mustAddSong(t, store, testSong("Muse", "Hysteria"))
+
+		song, err := store.GetSongByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetSongByID: %v", err)
+		}
+		if song.ID != id || song.Group != "Muse" || song.Song != "Hysteria" || song.ReleaseDate != "01.12.2003" {
+			t.Errorf("GetSongByID = %+v", song)
+		}
+		if song.CreatedAt.IsZero() || song.UpdatedAt.IsZero() {
+			t.Errorf("время создания и изменения не заполнено: %+v", song)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		store := newStore(t)
+		if _, err := store.GetSongByID(ctx, 42); !errors.Is(err, repository.ErrSongNotFound) {
+			t.Errorf("GetSongByID: ошибка %v, ожидалась ErrSongNotFound", err)
+		}
+		missing := testSong("Muse", "Uprising")
+		missing.ID = 42
+		if err := store.UpdateSong(ctx, missing); !errors.Is(err, repository.ErrSongNotFound) {
+			t.Errorf("UpdateSong: ошибка %v, ожидалась ErrSongNotFound", err)
+		}
+		if err := store.DeleteSong(ctx, 42); !errors.Is(err, repository.ErrSongNotFound) {
+			t.Errorf("DeleteSong: ошибка %v, ожидалась ErrSongNotFound", err)
+		}
+	})
+
+	t.Run("SongExists", func(t *testing.T) {
+		store := newStore(t)
+		mustAddSong(t, store, testSong("Muse", "Hysteria"))
+		other := mustAddSong(t, store, testSong("Muse", "Uprising"))
+
+		if _, err := store.AddSong(ctx, testSong("Muse", "Hysteria")); !errors.Is(err, repository.ErrSongExists) {
+			t.Errorf("AddSong: ошибка %v, ожидалась ErrSongExists", err)
+		}
+		renamed := testSong("Muse", "Hysteria")
+		renamed.ID = other
+		if err := store.UpdateSong(ctx, renamed); !errors.Is(err, repository.ErrSongExists) {
+			t.Errorf("UpdateSong: ошибка %v, ожидалась ErrSongExists", err)
+		}
+	})
+
+	t.Run("FilterAndOrder", func(t *testing.T) {
+		store := newStore(t)
+		first := mustAddSong(t, store, testSong("Muse", "Hysteria"))
+		mustAddSong(t, store, testSong("Queen", "Bohemian Rhapsody"))
+		third := mustAddSong(t, store, testSong("Muse", "Uprising"))
+
+		songs, err := store.GetSongs(ctx, domain.SongFilter{Group: "Mus"}, 0, 10)
+		if err != nil {
+			t.Fatalf("GetSongs: %v", err)
+		}
+		if ids := songIDs(songs); !slices.Equal(ids, []int{first, third}) {
+			t.Errorf("GetSongs(group=Mus) = %v, ожидалось %v", ids, []int{first, third})
+		}
+
+		songs, err = store.GetSongs(ctx, domain.SongFilter{Song: "Rhapsody"}, 0, 10)
+		if err != nil {
+			t.Fatalf("GetSongs: %v", err)
+		}
+		if len(songs) != 1 || songs[0].Group != "Queen" {
+			t.Errorf("GetSongs(song=Rhapsody) = %+v", songs)
+		}
+
+		songs, err = store.GetSongs(ctx, domain.SongFilter{}, 1, 1)
+		if err != nil {
+			t.Fatalf("GetSongs: %v", err)
+		}
+		if len(songs) != 1 || songs[0].Song != "Bohemian Rhapsody" {
+			t.Errorf("GetSongs(offset=1, limit=1) = %+v", songs)
+		}
+
+		var streamed []domain.Song
+		err = store.StreamSongs(ctx, domain.SongFilter{Group: "Muse"}, func(song domain.Song) error {
+			streamed = append(streamed, song)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("StreamSongs: %v", err)
+		}
+		if ids := songIDs(streamed); !slices.Equal(ids, []int{first, third}) {
+			t.Errorf("StreamSongs(group=Muse) = %v, ожидалось %v", ids, []int{first, third})
+		}
+	})
+
+	t.Run("UpdatedSince", func(t *testing.T) {
+		store := newStore(t)
+		first := mustAddSong(t, store, testSong("Muse", "Hysteria"))
+		second := mustAddSong(t, store, testSong("Muse", "Uprising"))
+		before := mustGetSong(t, store, second)
+
+		time.Sleep(20 * time.Millisecond)
+		updated := testSong("Muse", "Uprising")
+		updated.ID, updated.Text = second, "Paranoia is in bloom"
+		if err := store.UpdateSong(ctx, updated); err != nil {
+			t.Fatalf("UpdateSong: %v", err)
+		}
+		after := mustGetSong(t, store, second)
+		if !after.UpdatedAt.After(before.UpdatedAt) {
+			t.Errorf("время изменения %v не позже прежнего %v", after.UpdatedAt, before.UpdatedAt)
+		}
+		if created := mustGetSong(t, store, first); !created.UpdatedAt.Equal(created.CreatedAt) {
+			t.Errorf("время изменения неизмененной песни %v, создания %v", created.UpdatedAt, created.CreatedAt)
+		}
+
+		songs, err := store.GetSongs(ctx, domain.SongFilter{UpdatedSince: before.UpdatedAt}, 0, 10)
+		if err != nil {
+			t.Fatalf("GetSongs: %v", err)
+		}
+		if ids := songIDs(songs); !slices.Equal(ids, []int{second}) {
+			t.Errorf("GetSongs(updated_since) = %v, ожидалось [%d]", ids, second)
+		}
+		songs, err = store.GetSongs(ctx, domain.SongFilter{UpdatedSince: after.UpdatedAt}, 0, 10)
+		if err != nil {
+			t.Fatalf("GetSongs: %v", err)
+		}
+		if len(songs) != 0 {
+			t.Errorf("GetSongs(updated_since=последнее изменение) = %v, ожидалось пусто", songIDs(songs))
+		}
+	})
+
+	t.Run("InsertSongsDryRun", func(t *testing.T) {
+		store := newStore(t)
+		mustAddSong(t, store, testSong("Muse", "Hysteria"))
+		batch := []domain.Song{testSong("Muse", "Hysteria"), testSong("Queen", "Bohemian Rhapsody")}
+
+		created, err := store.InsertSongs(ctx, batch, true)
+		if err != nil {
+			t.Fatalf("InsertSongs(dryRun): %v", err)
+		}
+		if !slices.Equal(created, []bool{false, true}) {
+			t.Errorf("InsertSongs(dryRun) = %v, ожидалось [false true]", created)
+		}
+		if total := countSongs(t, store); total != 1 {
+			t.Errorf("после пробного импорта песен %d, ожидалась 1", total)
+		}
+		_, changes, err := store.GetChanges(ctx, 0, 10)
+		if err != nil {
+			t.Fatalf("GetChanges: %v", err)
+		}
+		if len(changes) != 1 {
+			t.Errorf("после пробного импорта в журнале %d изменений, ожидалось 1", len(changes))
+		}
+
+		created, err = store.InsertSongs(ctx, batch, false)
+		if err != nil {
+			t.Fatalf("InsertSongs: %v", err)
+		}
+		if !slices.Equal(created, []bool{false, true}) {
+			t.Errorf("InsertSongs = %v, ожидалось [false true]", created)
+		}
+		if total := countSongs(t, store); total != 2 {
+			t.Errorf("после импорта песен %d, ожидалось 2", total)
+		}
+	})
+
+	t.Run("ChangeLog", func(t *testing.T) {
+		store := newStore(t)
+		id := mustAddSong(t, store, testSong("Muse", "Hysteria"))
+		updated := testSong("Muse", "Hysteria")
+		updated.ID, updated.Text = id, "It's bugging me"
+		if err := store.UpdateSong(ctx, updated); err != nil {
+			t.Fatalf("UpdateSong: %v", err)
+		}
+		if err := store.DeleteSong(ctx, id); err != nil {
+			t.Fatalf("DeleteSong: %v", err)
+		}
+
+		state, changes, err := store.GetChanges(ctx, 0, 10)
+		if err != nil {
+			t.Fatalf("GetChanges: %v", err)
+		}
+		if len(changes) != 3 {
+			t.Fatalf("в журнале %d изменений, ожидалось 3: %+v", len(changes), changes)
+		}
+		ops := []string{domain.ChangeCreated, domain.ChangeUpdated, domain.ChangeDeleted}
+		for i, change := range changes {
+			if change.Op != ops[i] || change.ID != id || change.Group != "Muse" {
+				t.Errorf("изменение %d = %+v, ожидалось %s песни %d группы Muse", i, change, ops[i], id)
+			}
+			if i > 0 && change.Seq <= changes[i-1].Seq {
+				t.Errorf("номера изменений не возрастают: %d после %d", change.Seq, changes[i-1].Seq)
+			}
+		}
+		if changes[1].Song == nil || changes[1].Song.Text != "It's bugging me" {
+			t.Errorf("изменение updated не содержит новых данных песни: %+v", changes[1].Song)
+		}
+		if changes[2].Song != nil {
+			t.Errorf("изменение deleted содержит песню: %+v", changes[2].Song)
+		}
+		if state.LogID == "" || state.Latest != changes[2].Seq {
+			t.Errorf("состояние журнала = %+v, последнее изменение %d", state, changes[2].Seq)
+		}
+
+		_, rest, err := store.GetChanges(ctx, changes[0].Seq, 10)
+		if err != nil {
+			t.Fatalf("GetChanges(after): %v", err)
+		}
+		if len(rest) != 2 || rest[0].Seq != changes[1].Seq {
+			t.Errorf("GetChanges(after=%d) = %+v", changes[0].Seq, rest)
+		}
+	})
+
+	t.Run("PruneChanges", func(t *testing.T) {
+		store := newStore(t)
+		mustAddSong(t, store, testSong("Muse", "Hysteria"))
+		mustAddSong(t, store, testSong("Muse", "Uprising"))
+		time.Sleep(20 * time.Millisecond)
+		cutoff := time.Now()
+		time.Sleep(20 * time.Millisecond)
+		mustAddSong(t, store, testSong("Queen", "Bohemian Rhapsody"))
+
+		_, all, err := store.GetChanges(ctx, 0, 10)
+		if err != nil {
+			t.Fatalf("GetChanges: %v", err)
+		}
+		pruned, err := store.PruneChanges(ctx, cutoff)
+		if err != nil {
+			t.Fatalf("PruneChanges: %v", err)
+		}
+		if pruned != 2 {
+			t.Errorf("PruneChanges = %d, ожидалось 2", pruned)
+		}
+
+		state, changes, err := store.GetChanges(ctx, 0, 10)
+		if err != nil {
+			t.Fatalf("GetChanges: %v", err)
+		}
+		if len(changes) != 1 || changes[0].Song == nil || changes[0].Song.Song != "Bohemian Rhapsody" {
+			t.Errorf("после очистки в журнале %+v, ожидалось одно изменение", changes)
+		}
+		if state.PrunedThrough != all[1].Seq || state.Latest != all[2].Seq {
+			t.Errorf("состояние журнала = %+v, ожидалось pruned_through=%d, latest=%d", state, all[1].Seq, all[2].Seq)
+		}
+
+		// Повторная очистка с тем же сроком ничего не удаляет
+		if pruned, err := store.PruneChanges(ctx, cutoff); err != nil || pruned != 0 {
+			t.Errorf("повторный PruneChanges = %d, %v; ожидалось 0", pruned, err)
+		}
+	})
+
+	t.Run("WebhookEnqueue", func(t *testing.T) {
+		store := newStore(t)
+		webhook, err := store.CreateWebhook(ctx, domain.Webhook{
+			URL:    "https://search.example.com/hooks/songs",
+			Events: []string{domain.EventSongCreated},
+			Secret: "secret",
+		})
+		if err != nil {
+			t.Fatalf("CreateWebhook: %v", err)
+		}
+		id := mustAddSong(t, store, testSong("Muse", "Hysteria"))
+		// Изменение не входит в подписку и не ставится в очередь
+		updated := testSong("Muse", "Hysteria")
+		updated.ID, updated.Text = id, "It's bugging me"
+		if err := store.UpdateSong(ctx, updated); err != nil {
+			t.Fatalf("UpdateSong: %v", err)
+		}
+
+		deliveries, err := store.GetDeliveries(ctx, webhook.ID, domain.DeliveryPending, 0, 10)
+		if err != nil {
+			t.Fatalf("GetDeliveries: %v", err)
+		}
+		if len(deliveries) != 1 {
+			t.Fatalf("в очереди %d доставок, ожидалась 1: %+v", len(deliveries), deliveries)
+		}
+		if deliveries[0].Event != domain.EventSongCreated || deliveries[0].Attempts != 0 {
+			t.Errorf("доставка = %+v", deliveries[0])
+		}
+
+		claimed, err := store.ClaimDeliveries(ctx, time.Now().Add(time.Minute), time.Minute, 10)
+		if err != nil {
+			t.Fatalf("ClaimDeliveries: %v", err)
+		}
+		if len(claimed) != 1 || claimed[0].ID != deliveries[0].ID {
+			t.Errorf("ClaimDeliveries = %+v, ожидалась доставка %d", claimed, deliveries[0].ID)
+		}
+		// Взятая доставка отложена на время аренды
+		claimed, err = store.ClaimDeliveries(ctx, time.Now().Add(time.Minute), time.Minute, 10)
+		if err != nil {
+			t.Fatalf("ClaimDeliveries: %v", err)
+		}
+		if len(claimed) != 0 {
+			t.Errorf("повторный ClaimDeliveries = %+v, ожидалось пусто", claimed)
+		}
+	})
+
+	t.Run("WebhookEnqueueLargeBatch", func(t *testing.T) {
+		store := newStore(t)
+		var webhooks []domain.Webhook
+		for i := 0; i < 45; i++ {
+			webhook, err := store.CreateWebhook(ctx, domain.Webhook{
+				URL:    fmt.Sprintf("https://search%d.example.com/hooks/songs", i),
+				Events: []string{domain.EventSongCreated},
+				Secret: "secret",
+			})
+			if err != nil {
+				t.Fatalf("CreateWebhook: %v", err)
+			}
+			webhooks = append(webhooks, webhook)
+		}
+
+		// 500 песен на 45 вебхуков дают 22500 доставок: по три параметра
+		// на строку это больше лимита и PostgreSQL (65535), и SQLite (32766)
+		const songs = 500
+		batch := make([]domain.Song, songs)
+		for i := range batch {
+			batch[i] = testSong("Muse", fmt.Sprintf("Song %d", i))
+		}
+		if _, err := store.InsertSongs(ctx, batch, false); err != nil {
+			t.Fatalf("InsertSongs: %v", err)
+		}
+
+		for _, webhook := range webhooks {
+			deliveries, err := store.GetDeliveries(ctx, webhook.ID, domain.DeliveryPending, 0, 2*songs)
+			if err != nil {
+				t.Fatalf("GetDeliveries: %v", err)
+			}
+			if len(deliveries) != songs {
+				t.Errorf("вебхук %d: в очереди %d доставок, ожидалось %d", webhook.ID, len(deliveries), songs)
+			}
+		}
+	})
+
+	t.Run("TxRollback", func(t *testing.T) {
+		store := newStore(t)
+		errAbort := errors.New("отмена")
+
+		err := store.InTx(ctx, func(tx repository.SongStore) error {
+			mustAddSong(t, tx, testSong("Muse", "Hysteria"))
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Errorf("InTx: ошибка %v, ожидалась ошибка fn", err)
+		}
+		if total := countSongs(t, store); total != 0 {
+			t.Errorf("после отката транзакции песен %d, ожидалось 0", total)
+		}
+		if _, changes, err := store.GetChanges(ctx, 0, 10); err != nil || len(changes) != 0 {
+			t.Errorf("после отката транзакции журнал %+v, %v; ожидалось пусто", changes, err)
+		}
+	})
+
+	t.Run("Savepoint", func(t *testing.T) {
+		store := newStore(t)
+		errAbort := errors.New("отмена")
+
+		if err := store.Savepoint(ctx, "outside", func() error { return nil }); err == nil {
+			t.Error("Savepoint вне транзакции выполнен без ошибки")
+		}
+
+		err := store.InTx(ctx, func(tx repository.SongStore) error {
+			mustAddSong(t, tx, testSong("Muse", "Hysteria"))
+			err := tx.Savepoint(ctx, "song_2", func() error {
+				mustAddSong(t, tx, testSong("Queen", "Bohemian Rhapsody"))
+				return errAbort
+			})
+			if !errors.Is(err, errAbort) {
+				t.Errorf("Savepoint: ошибка %v, ожидалась ошибка fn", err)
+			}
+			// После отката к точке сохранения транзакция остается рабочей
+			mustAddSong(t, tx, testSong("Muse", "Uprising"))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("InTx: %v", err)
+		}
+
+		songs, err := store.GetSongs(ctx, domain.SongFilter{}, 0, 10)
+		if err != nil {
+			t.Fatalf("GetSongs: %v", err)
+		}
+		var names []string
+		for _, song := range songs {
+			names = append(names, song.Song)
+		}
+		if !slices.Equal(names, []string{"Hysteria", "Uprising"}) {
+			t.Errorf("после транзакции песни %v, ожидались [Hysteria Uprising]", names)
+		}
+	})
+}
+
+// testSong возвращает песню группы group с названием name и заполненными деталями.
+func testSong(group, name string) domain.Song {
+	return domain.Song{
+		Group:       group,
+		Song:        name,
+		ReleaseDate: "01.12.2003",
+		Text:        "Text",
+		Link:        "https://example.com/" + name,
+	}
+}
+
+func mustAddSong(t *testing.T, store repository.SongStore, song domain.Song) int {
+	t.Helper()
+	id, err := store.AddSong(context.Background(), song)
+	if err != nil {
+		t.Fatalf("AddSong(%s - %s): %v", song.Group, song.Song, err)
+	}
+	return id
+}
+
+func mustGetSong(t *testing.T, store repository.SongStore, id int) *domain.Song {
+	t.Helper()
+	song, err := store.GetSongByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetSongByID(%d): %v", id, err)
+	}
+	return song
+}
+
+// countSongs возвращает количество песен в хранилище.
+func countSongs(t *testing.T, store repository.SongStore) int {
+	t.Helper()
+	songs, err := store.GetSongs(context.Background(), domain.SongFilter{}, 0, 1000)
+	if err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+	return len(songs)
+}
+
+func songIDs(songs []domain.Song) []int {
+	ids := make([]int, len(songs))
+	for i, song := range songs {
+		ids[i] = song.ID
+	}
+	return ids
+}
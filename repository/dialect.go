@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// dialect описывает различия SQL между поддерживаемыми базами данных.
+type dialect struct {
+	name string
+	// system — значение атрибута db.system в спанах запросов.
+	system attribute.KeyValue
+	// contains возвращает условие "column содержит параметр номер param".
+	contains func(column string, param int) string
+	// after возвращает условие "время в column позже параметра номер param".
+	after func(column string, param int) string
+	// before возвращает условие "время в column раньше параметра номер param".
+	before func(column string, param int) string
+	// cursors сообщает, что выгрузка может идти через серверный курсор.
+	cursors bool
+	// snapshot — параметры транзакции, все запросы которой видят один снимок данных.
+	snapshot *sql.TxOptions
+	// lockChangeLog — запрос, который до конца транзакции блокирует запись в
+	// журнал изменений, чтобы номера изменений шли в порядке фиксации.
+	// Пустая строка — база данных сама выполняет записи по очереди.
+	lockChangeLog string
+	// isUniqueViolation распознает нарушение уникального индекса.
+	isUniqueViolation func(err error) bool
+}
+
+var postgresDialect = dialect{
+	name:   "postgres",
+	system: semconv.DBSystemPostgreSQL,
+	contains: func(column string, param int) string {
+		return fmt.Sprintf("strpos(%s, $%d) > 0", column, param)
+	},
+	after: func(column string, param int) string {
+		return fmt.Sprintf("%s > $%d", column, param)
+	},
+	before: func(column string, param int) string {
+		return fmt.Sprintf("%s < $%d", column, param)
+	},
+	cursors:       true,
+	snapshot:      &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead},
+	lockChangeLog: "SELECT pg_advisory_xact_lock(hashtext('song_changes'))",
+	isUniqueViolation: func(err error) bool {
+		var pqErr *pq.Error
+		return errors.As(err, &pqErr) && pqErr.Code == "23505"
+	},
+}
+
+// sqliteDialect не поддерживает серверные курсоры: SQLite и так читает
+// строки по одной, не загружая результат целиком. Время хранится текстом в
+// разных форматах, поэтому сравнивается через julianday. Читающая транзакция
+// SQLite и так видит один снимок данных, а записи выполняются по очереди.
+var sqliteDialect = dialect{
+	name:   "sqlite",
+	system: semconv.DBSystemSqlite,
+	contains: func(column string, param int) string {
+		return fmt.Sprintf("instr(%s, $%d) > 0", column, param)
+	},
+	after: func(column string, param int) string {
+		return fmt.Sprintf("julianday(%s) > julianday($%d)", column, param)
+	},
+	before: func(column string, param int) string {
+		return fmt.Sprintf("julianday(%s) < julianday($%d)", column, param)
+	},
+	cursors:  false,
+	snapshot: nil,
+	isUniqueViolation: func(err error) bool {
+		var sqliteErr *sqlite.Error
+		return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	},
+}
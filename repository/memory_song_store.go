@@ -0,0 +1,442 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"song-library/auth"
+	"song-library/domain"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemorySongStore хранит песни в памяти процесса. Семантика совпадает с
+// SongRepository: фильтры, порядок по ID, уникальность пары группа/название
+// и ошибки ErrSongNotFound. Транзакции выполняются последовательно под общей
+// блокировкой, откат восстанавливает снимок данных.
+type MemorySongStore struct {
+	mu   *sync.RWMutex
+	data *memoryData
+	inTx bool
+	log  *slog.Logger
+}
+
+type memoryData struct {
+	songs  map[int]domain.Song
+	index  map[[2]string]int // Пара группа/название -> ID
+	nextID int
+
+	// Журнал изменений: записи в порядке номеров, последний выданный номер
+	// и наибольший номер, удаленный по сроку хранения
+	logID         string
+	changes       []domain.SongChange
+	lastSeq       int64
+	prunedThrough int64
+
+	// Подписки на события и очередь их доставок в порядке ID
+	webhooks       map[int]domain.Webhook
+	nextWebhookID  int
+	deliveries     []domain.WebhookDelivery
+	nextDeliveryID int64
+}
+
+// NewMemorySongStore создает пустое хранилище песен в памяти.
+func NewMemorySongStore(logger *slog.Logger) *MemorySongStore {
+	logID := make([]byte, 16)
+	rand.Read(logID)
+	return &MemorySongStore{
+		mu: &sync.RWMutex{},
+		data: &memoryData{
+			songs:  make(map[int]domain.Song),
+			index:  make(map[[2]string]int),
+			nextID: 1,
+			logID:  hex.EncodeToString(logID),
+
+			webhooks:       make(map[int]domain.Webhook),
+			nextWebhookID:  1,
+			nextDeliveryID: 1,
+		},
+		log: logger,
+	}
+}
+
+func (store *MemorySongStore) GetSongs(ctx context.Context, filter domain.SongFilter, offset, limit int) ([]domain.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	store.rlock()
+	defer store.runlock()
+
+	matched := store.data.filtered(filter)
+	if offset >= len(matched) {
+		return nil, nil
+	}
+	end := offset + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	store.log.DebugContext(ctx, "успешно выполнен GetSongs", "offset", offset, "limit", limit)
+	return matched[offset:end], nil
+}
+
+func (store *MemorySongStore) StreamSongs(ctx context.Context, filter domain.SongFilter, fn func(domain.Song) error) error {
+	store.rlock()
+	matched := store.data.filtered(filter)
+	store.runlock()
+
+	for _, song := range matched {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(song); err != nil {
+			return err
+		}
+	}
+
+	store.log.DebugContext(ctx, "успешно выполнен StreamSongs", "count", len(matched))
+	return nil
+}
+
+func (store *MemorySongStore) GetSongByID(ctx context.Context, id int) (*domain.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	store.rlock()
+	defer store.runlock()
+
+	song, ok := store.data.songs[id]
+	if !ok {
+		store.log.WarnContext(ctx, "песня не найдена", "song_id", id)
+		return nil, ErrSongNotFound
+	}
+
+	store.log.DebugContext(ctx, "песня успешно получена", "song_id", id)
+	return &song, nil
+}
+
+func (store *MemorySongStore) AddSong(ctx context.Context, song domain.Song) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	store.lock()
+	defer store.unlock()
+
+	if store.data.exists(song.Group, song.Song, 0) {
+		store.log.WarnContext(ctx, "ошибка добавления песни", "group", song.Group, "song", song.Song, "error", ErrSongExists)
+		return 0, ErrSongExists
+	}
+
+	song.ID = store.data.insert(stamped(ctx, song))
+	store.data.record(domain.ChangeCreated, domain.EventSongCreated, song.ID)
+	store.log.DebugContext(ctx, "песня успешно добавлена", "song_id", song.ID, "group", song.Group, "song", song.Song)
+	return song.ID, nil
+}
+
+func (store *MemorySongStore) UpdateSong(ctx context.Context, song domain.Song) error {
+	return store.updateSong(ctx, song, domain.EventSongUpdated)
+}
+
+func (store *MemorySongStore) EnrichSong(ctx context.Context, song domain.Song) error {
+	return store.updateSong(ctx, song, domain.EventSongEnriched)
+}
+
+func (store *MemorySongStore) updateSong(ctx context.Context, song domain.Song, event string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	store.lock()
+	defer store.unlock()
+
+	current, ok := store.data.songs[song.ID]
+	if !ok {
+		store.log.WarnContext(ctx, "песня для обновления не найдена", "song_id", song.ID)
+		return ErrSongNotFound
+	}
+	if store.data.exists(song.Group, song.Song, song.ID) {
+		store.log.WarnContext(ctx, "ошибка обновления песни", "song_id", song.ID, "error", ErrSongExists)
+		return ErrSongExists
+	}
+
+	// Как и триггер базы данных, запись без изменений не сдвигает время обновления
+	song.CreatedAt, song.CreatedBy = current.CreatedAt, current.CreatedBy
+	song.UpdatedAt, song.UpdatedBy = current.UpdatedAt, auth.User(ctx)
+	if song != current {
+		song.UpdatedAt = time.Now().UTC()
+	}
+
+	store.data.remove(song.ID)
+	store.data.songs[song.ID] = song
+	store.data.index[[2]string{song.Group, song.Song}] = song.ID
+	store.data.record(domain.ChangeUpdated, event, song.ID)
+	store.log.DebugContext(ctx, "песня успешно обновлена", "song_id", song.ID)
+	return nil
+}
+
+func (store *MemorySongStore) DeleteSong(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	store.lock()
+	defer store.unlock()
+
+	if _, ok := store.data.songs[id]; !ok {
+		store.log.WarnContext(ctx, "песня для удаления не найдена", "song_id", id)
+		return ErrSongNotFound
+	}
+
+	// Журнал изменений берет группу из еще не удаленной песни
+	store.data.record(domain.ChangeDeleted, domain.EventSongDeleted, id)
+	store.data.remove(id)
+	store.log.DebugContext(ctx, "песня успешно удалена", "song_id", id)
+	return nil
+}
+
+func (store *MemorySongStore) CountSongs(ctx context.Context) (domain.SongCounts, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.SongCounts{}, err
+	}
+	store.rlock()
+	defer store.runlock()
+
+	counts := domain.SongCounts{Total: len(store.data.songs)}
+	for _, song := range store.data.songs {
+		if song.NeedsEnrichment() {
+			counts.PendingEnrichment++
+		}
+	}
+	return counts, nil
+}
+
+func (store *MemorySongStore) InsertSongs(ctx context.Context, songs []domain.Song, dryRun bool) ([]bool, error) {
+	created := make([]bool, len(songs))
+	err := store.InTx(ctx, func(tx SongStore) error {
+		data := tx.(*MemorySongStore).data
+		for i, song := range songs {
+			if data.exists(song.Group, song.Song, 0) {
+				continue
+			}
+			data.record(domain.ChangeCreated, domain.EventSongCreated, data.insert(stamped(ctx, song)))
+			created[i] = true
+		}
+		if dryRun {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	store.log.DebugContext(ctx, "успешно выполнен InsertSongs", "count", len(songs), "dry_run", dryRun)
+	return created, nil
+}
+
+func (store *MemorySongStore) GetChanges(ctx context.Context, after int64, limit int) (domain.ChangeLogState, []domain.SongChange, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChangeLogState{}, nil, err
+	}
+	store.rlock()
+	defer store.runlock()
+
+	data := store.data
+	state := domain.ChangeLogState{LogID: data.logID, PrunedThrough: data.prunedThrough, Latest: data.prunedThrough}
+	if len(data.changes) > 0 {
+		state.Latest = data.changes[len(data.changes)-1].Seq
+	}
+
+	start := sort.Search(len(data.changes), func(i int) bool { return data.changes[i].Seq > after })
+	end := min(start+limit, len(data.changes))
+	changes := slices.Clone(data.changes[start:end])
+
+	store.log.DebugContext(ctx, "успешно выполнен GetChanges", "after", after, "count", len(changes))
+	return state, changes, nil
+}
+
+func (store *MemorySongStore) PruneChanges(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	store.lock()
+	defer store.unlock()
+
+	data := store.data
+	n := sort.Search(len(data.changes), func(i int) bool { return !data.changes[i].ChangedAt.Before(before) })
+	if n > 0 {
+		data.prunedThrough = max(data.prunedThrough, data.changes[n-1].Seq)
+		data.changes = slices.Clone(data.changes[n:])
+	}
+
+	store.log.DebugContext(ctx, "успешно выполнен PruneChanges", "pruned", n)
+	return int64(n), nil
+}
+
+func (store *MemorySongStore) InTx(ctx context.Context, fn func(tx SongStore) error) error {
+	if store.inTx {
+		return fn(store)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	snapshot := store.data.clone()
+	tx := &MemorySongStore{mu: store.mu, data: store.data, inTx: true, log: store.log}
+	if err := fn(tx); err != nil {
+		store.data.restore(snapshot)
+		if errors.Is(err, errRollback) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (store *MemorySongStore) Savepoint(ctx context.Context, name string, fn func() error) error {
+	if !store.inTx {
+		return savepointOutsideTxError(name)
+	}
+
+	snapshot := store.data.clone()
+	if err := fn(); err != nil {
+		store.data.restore(snapshot)
+		return err
+	}
+	return nil
+}
+
+// Внутри транзакции блокировка уже удерживается InTx.
+func (store *MemorySongStore) lock() {
+	if !store.inTx {
+		store.mu.Lock()
+	}
+}
+
+func (store *MemorySongStore) unlock() {
+	if !store.inTx {
+		store.mu.Unlock()
+	}
+}
+
+func (store *MemorySongStore) rlock() {
+	if !store.inTx {
+		store.mu.RLock()
+	}
+}
+
+func (store *MemorySongStore) runlock() {
+	if !store.inTx {
+		store.mu.RUnlock()
+	}
+}
+
+// filtered возвращает копии песен, удовлетворяющих фильтру, в порядке ID.
+func (data *memoryData) filtered(filter domain.SongFilter) []domain.Song {
+	var songs []domain.Song
+	for _, song := range data.songs {
+		if filter.Group != "" && !strings.Contains(song.Group, filter.Group) {
+			continue
+		}
+		if filter.Song != "" && !strings.Contains(song.Song, filter.Song) {
+			continue
+		}
+		if filter.ReleaseDate != "" && song.ReleaseDate != filter.ReleaseDate {
+			continue
+		}
+		if !filter.UpdatedSince.IsZero() && !song.UpdatedAt.After(filter.UpdatedSince) {
+			continue
+		}
+		songs = append(songs, song)
+	}
+	sort.Slice(songs, func(i, j int) bool { return songs[i].ID < songs[j].ID })
+	return songs
+}
+
+// exists сообщает, есть ли другая песня (с ID, отличным от exceptID) с той же парой группа/название.
+func (data *memoryData) exists(group, song string, exceptID int) bool {
+	id, ok := data.index[[2]string{group, song}]
+	return ok && id != exceptID
+}
+
+// stamped заполняет время и автора добавления песни, как значения по умолчанию в таблице songs.
+func stamped(ctx context.Context, song domain.Song) domain.Song {
+	now := time.Now().UTC()
+	user := auth.User(ctx)
+	song.CreatedAt, song.UpdatedAt = now, now
+	song.CreatedBy, song.UpdatedBy = user, user
+	return song
+}
+
+func (data *memoryData) insert(song domain.Song) int {
+	song.ID = data.nextID
+	data.nextID++
+	data.songs[song.ID] = song
+	data.index[[2]string{song.Group, song.Song}] = song.ID
+	return song.ID
+}
+
+// record добавляет в журнал изменение op песни id со снимком ее текущих
+// данных и ставит событие event в очередь доставки подписанным вебхукам.
+// Удаление записывается до удаления песни: в журнале остается ее группа.
+func (data *memoryData) record(op, event string, id int) {
+	data.lastSeq++
+	change := domain.SongChange{Seq: data.lastSeq, Op: op, ID: id, ChangedAt: time.Now().UTC()}
+	if song, ok := data.songs[id]; ok {
+		change.Group = song.Group
+		if op != domain.ChangeDeleted {
+			change.Song = &song
+		}
+	}
+	data.changes = append(data.changes, change)
+	data.enqueue(event, change)
+}
+
+func (data *memoryData) remove(id int) {
+	song := data.songs[id]
+	delete(data.index, [2]string{song.Group, song.Song})
+	delete(data.songs, id)
+}
+
+// restore возвращает данные к снимку. Счетчики ID и номеров, как и
+// последовательности в PostgreSQL, при откате не уменьшаются.
+func (data *memoryData) restore(snapshot *memoryData) {
+	data.songs = snapshot.songs
+	data.index = snapshot.index
+	data.changes = snapshot.changes
+	data.prunedThrough = snapshot.prunedThrough
+	data.webhooks = snapshot.webhooks
+	data.deliveries = snapshot.deliveries
+}
+
+func (data *memoryData) clone() *memoryData {
+	songs := make(map[int]domain.Song, len(data.songs))
+	for id, song := range data.songs {
+		songs[id] = song
+	}
+	index := make(map[[2]string]int, len(data.index))
+	for key, id := range data.index {
+		index[key] = id
+	}
+	return &memoryData{
+		songs:         songs,
+		index:         index,
+		nextID:        data.nextID,
+		logID:         data.logID,
+		changes:       slices.Clone(data.changes),
+		lastSeq:       data.lastSeq,
+		prunedThrough: data.prunedThrough,
+
+		webhooks:       maps.Clone(data.webhooks),
+		nextWebhookID:  data.nextWebhookID,
+		deliveries:     slices.Clone(data.deliveries),
+		nextDeliveryID: data.nextDeliveryID,
+	}
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"song-library/auth"
+	"song-library/domain"
+)
+
+// enqueue ставит событие event по изменению change в очередь доставки
+// подписанным на него вебхукам.
+func (data *memoryData) enqueue(event string, change domain.SongChange) {
+	payload, err := json.Marshal(domain.WebhookEvent{Event: event, SongID: change.ID, Song: change.Song, OccurredAt: change.ChangedAt})
+	if err != nil {
+		// Песня всегда сериализуется; ошибка означает ошибку программиста
+		panic(err)
+	}
+
+	ids := slices.Sorted(maps.Keys(data.webhooks))
+	for _, id := range ids {
+		if !data.webhooks[id].Subscribed(event) {
+			continue
+		}
+		next := change.ChangedAt
+		data.deliveries = append(data.deliveries, domain.WebhookDelivery{
+			ID:            data.nextDeliveryID,
+			WebhookID:     id,
+			Event:         event,
+			Payload:       payload,
+			Status:        domain.DeliveryPending,
+			NextAttemptAt: &next,
+			CreatedAt:     change.ChangedAt,
+		})
+		data.nextDeliveryID++
+	}
+}
+
+func (store *MemorySongStore) CreateWebhook(ctx context.Context, webhook domain.Webhook) (domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Webhook{}, err
+	}
+	store.lock()
+	defer store.unlock()
+
+	webhook.ID = store.data.nextWebhookID
+	webhook.CreatedAt = time.Now().UTC()
+	webhook.CreatedBy = auth.User(ctx)
+	webhook.Events = slices.Clone(webhook.Events)
+	store.data.nextWebhookID++
+	store.data.webhooks[webhook.ID] = webhook
+
+	store.log.DebugContext(ctx, "вебхук создан", "webhook_id", webhook.ID)
+	return webhook, nil
+}
+
+func (store *MemorySongStore) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	store.rlock()
+	defer store.runlock()
+
+	var webhooks []domain.Webhook
+	for _, id := range slices.Sorted(maps.Keys(store.data.webhooks)) {
+		webhooks = append(webhooks, store.data.webhooks[id])
+	}
+	return webhooks, nil
+}
+
+func (store *MemorySongStore) GetWebhook(ctx context.Context, id int) (*domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	store.rlock()
+	defer store.runlock()
+
+	webhook, ok := store.data.webhooks[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	return &webhook, nil
+}
+
+func (store *MemorySongStore) DeleteWebhook(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	store.lock()
+	defer store.unlock()
+
+	if _, ok := store.data.webhooks[id]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(store.data.webhooks, id)
+	store.data.deliveries = slices.DeleteFunc(slices.Clone(store.data.deliveries), func(delivery domain.WebhookDelivery) bool {
+		return delivery.WebhookID == id
+	})
+
+	store.log.DebugContext(ctx, "вебхук удален", "webhook_id", id)
+	return nil
+}
+
+func (store *MemorySongStore) GetDeliveries(ctx context.Context, webhookID int, status string, before int64, limit int) ([]domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	store.rlock()
+	defer store.runlock()
+
+	if _, ok := store.data.webhooks[webhookID]; !ok {
+		return nil, ErrWebhookNotFound
+	}
+
+	var deliveries []domain.WebhookDelivery
+	for i := len(store.data.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		delivery := store.data.deliveries[i]
+		if delivery.WebhookID != webhookID || (status != "" && delivery.Status != status) || (before > 0 && delivery.ID >= before) {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (store *MemorySongStore) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	store.lock()
+	defer store.unlock()
+
+	var claimed []domain.WebhookDelivery
+	for i := range store.data.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		delivery := &store.data.deliveries[i]
+		if delivery.Status != domain.DeliveryPending || !delivery.NextAttemptAt.Before(now) {
+			continue
+		}
+		next := now.Add(lease)
+		delivery.NextAttemptAt = &next
+		claimed = append(claimed, *delivery)
+	}
+	return claimed, nil
+}
+
+func (store *MemorySongStore) CompleteDelivery(ctx context.Context, attempt domain.DeliveryAttempt) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	store.lock()
+	defer store.unlock()
+
+	delivery := store.data.delivery(attempt.DeliveryID)
+	if delivery == nil {
+		// Подписку удалили во время попытки
+		return nil
+	}
+	status, next, deliveredAt := deliveryOutcome(attempt)
+	at := attempt.At
+	delivery.Status = status
+	delivery.Attempts++
+	delivery.LastAttemptAt = &at
+	delivery.ResponseStatus = attempt.ResponseStatus
+	delivery.LastError = attempt.Error
+	delivery.DeliveredAt = deliveredAt
+	delivery.NextAttemptAt = nil
+	if status == domain.DeliveryPending {
+		delivery.NextAttemptAt = &next
+	}
+	return nil
+}
+
+func (store *MemorySongStore) Redeliver(ctx context.Context, webhookID int, deliveryID int64) (*domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	store.lock()
+	defer store.unlock()
+
+	delivery := store.data.delivery(deliveryID)
+	if delivery == nil || delivery.WebhookID != webhookID {
+		return nil, ErrDeliveryNotFound
+	}
+	next := time.Now().UTC()
+	delivery.Status = domain.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &next
+	delivery.DeliveredAt = nil
+
+	redelivered := *delivery
+	return &redelivered, nil
+}
+
+func (store *MemorySongStore) PruneDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	store.lock()
+	defer store.unlock()
+
+	count := len(store.data.deliveries)
+	store.data.deliveries = slices.DeleteFunc(slices.Clone(store.data.deliveries), func(delivery domain.WebhookDelivery) bool {
+		return delivery.Status != domain.DeliveryPending && delivery.CreatedAt.Before(before)
+	})
+	return int64(count - len(store.data.deliveries)), nil
+}
+
+// delivery находит доставку по ID; доставки упорядочены по ID.
+func (data *memoryData) delivery(id int64) *domain.WebhookDelivery {
+	i := sort.Search(len(data.deliveries), func(i int) bool { return data.deliveries[i].ID >= id })
+	if i == len(data.deliveries) || data.deliveries[i].ID != id {
+		return nil
+	}
+	return &data.deliveries[i]
+}
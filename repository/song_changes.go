@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"song-library/domain"
+)
+
+// recordChanges записывает в журнал изменение op (добавление или изменение)
+// песен ids со снимками их текущих данных и ставит событие event в очередь
+// доставки подписанным вебхукам.
+func (repo *SongRepository) recordChanges(ctx context.Context, op, event string, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	snapshots, err := repo.songSnapshots(ctx, ids)
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка чтения снимков песен для журнала изменений", "error", err)
+		return err
+	}
+
+	changes := make([]domain.SongChange, len(ids))
+	for i, id := range ids {
+		changes[i] = domain.SongChange{Op: op, ID: id}
+		if song, ok := snapshots[id]; ok {
+			changes[i].Group = song.Group
+			changes[i].Song = &song
+		}
+	}
+	return repo.logChanges(ctx, event, changes)
+}
+
+// recordDeletion записывает в журнал удаление песни id группы group и
+// ставит событие song.deleted в очередь доставки подписанным вебхукам.
+func (repo *SongRepository) recordDeletion(ctx context.Context, id int, group string) error {
+	return repo.logChanges(ctx, domain.EventSongDeleted, []domain.SongChange{{Op: domain.ChangeDeleted, ID: id, Group: group}})
+}
+
+// logChanges добавляет изменения changes в журнал и ставит событие event в
+// очередь вебхуков. Вызывается в транзакции изменения, поэтому записи
+// журнала и очереди фиксируются и откатываются вместе с ним.
+func (repo *SongRepository) logChanges(ctx context.Context, event string, changes []domain.SongChange) error {
+	if repo.dialect.lockChangeLog != "" {
+		if _, err := repo.exec.ExecContext(ctx, repo.dialect.lockChangeLog); err != nil {
+			repo.log.ErrorContext(ctx, "ошибка блокировки журнала изменений", "error", err)
+			return err
+		}
+	}
+
+	var query strings.Builder
+	query.WriteString("INSERT INTO song_changes (song_id, operation, group_name, song) VALUES ")
+	args := make([]interface{}, 0, len(changes)*4)
+	for i, change := range changes {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		n := i * 4
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4)
+
+		var snapshot interface{}
+		if change.Song != nil {
+			data, err := json.Marshal(change.Song)
+			if err != nil {
+				return err
+			}
+			snapshot = string(data)
+		}
+		args = append(args, change.ID, change.Op, change.Group, snapshot)
+	}
+
+	if _, err := repo.exec.ExecContext(ctx, query.String(), args...); err != nil {
+		repo.log.ErrorContext(ctx, "ошибка записи в журнал изменений", "count", len(changes), "error", err)
+		return err
+	}
+	return repo.enqueueWebhookEvents(ctx, event, changes)
+}
+
+// songSnapshots возвращает текущие данные песен ids.
+func (repo *SongRepository) songSnapshots(ctx context.Context, ids []int) (map[int]domain.Song, error) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM songs WHERE id IN (%s)", songColumns, strings.Join(placeholders, ", "))
+	rows, err := repo.exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make(map[int]domain.Song, len(ids))
+	for rows.Next() {
+		song, err := scanSong(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots[song.ID] = song
+	}
+	return snapshots, rows.Err()
+}
+
+// GetChanges возвращает состояние журнала изменений и до limit изменений с
+// номерами больше after в порядке фиксации. Состояние и изменения читаются
+// из одного снимка данных.
+func (repo *SongRepository) GetChanges(ctx context.Context, after int64, limit int) (domain.ChangeLogState, []domain.SongChange, error) {
+	var state domain.ChangeLogState
+	var changes []domain.SongChange
+	err := repo.withTx(ctx, repo.dialect.snapshot, func(tx *SongRepository) error {
+		var err error
+		if state, err = tx.changeLogState(ctx); err != nil {
+			return err
+		}
+		changes, err = tx.changesAfter(ctx, after, limit)
+		return err
+	})
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка чтения журнала изменений", "after", after, "error", err)
+		return domain.ChangeLogState{}, nil, err
+	}
+
+	repo.log.DebugContext(ctx, "успешно выполнен GetChanges", "after", after, "count", len(changes))
+	return state, changes, nil
+}
+
+// changeLogState читает идентификатор журнала и границы номеров изменений.
+func (repo *SongRepository) changeLogState(ctx context.Context) (domain.ChangeLogState, error) {
+	var state domain.ChangeLogState
+	err := repo.exec.QueryRowContext(ctx,
+		"SELECT log_id, pruned_through, COALESCE((SELECT MAX(id) FROM song_changes), 0) FROM song_changes_state",
+	).Scan(&state.LogID, &state.PrunedThrough, &state.Latest)
+	if err != nil {
+		return domain.ChangeLogState{}, fmt.Errorf("ошибка чтения состояния журнала изменений: %w", err)
+	}
+	state.Latest = max(state.Latest, state.PrunedThrough)
+	return state, nil
+}
+
+// changesAfter читает до limit изменений с номерами больше after.
+func (repo *SongRepository) changesAfter(ctx context.Context, after int64, limit int) ([]domain.SongChange, error) {
+	rows, err := repo.exec.QueryContext(ctx,
+		"SELECT id, song_id, operation, group_name, song, changed_at FROM song_changes WHERE id > $1 ORDER BY id LIMIT $2",
+		after, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []domain.SongChange
+	for rows.Next() {
+		var change domain.SongChange
+		var snapshot sql.NullString
+		if err := rows.Scan(&change.Seq, &change.ID, &change.Op, &change.Group, &snapshot, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		if snapshot.Valid {
+			change.Song = &domain.Song{}
+			if err := json.Unmarshal([]byte(snapshot.String), change.Song); err != nil {
+				return nil, fmt.Errorf("ошибка разбора снимка песни в изменении %d: %w", change.Seq, err)
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// PruneChanges удаляет из журнала изменения, сделанные раньше before, и
+// возвращает количество удаленных записей. Токены синхронизации, указывающие
+// на удаленные изменения, после этого считаются устаревшими.
+func (repo *SongRepository) PruneChanges(ctx context.Context, before time.Time) (int64, error) {
+	var pruned int64
+	err := repo.withTx(ctx, nil, func(tx *SongRepository) error {
+		var through int64
+		err := tx.exec.QueryRowContext(ctx,
+			"SELECT COALESCE(MAX(id), 0) FROM song_changes WHERE "+tx.dialect.before("changed_at", 1), before,
+		).Scan(&through)
+		if err != nil || through == 0 {
+			return err
+		}
+
+		res, err := tx.exec.ExecContext(ctx, "DELETE FROM song_changes WHERE id <= $1", through)
+		if err != nil {
+			return err
+		}
+		if pruned, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		_, err = tx.exec.ExecContext(ctx, "UPDATE song_changes_state SET pruned_through = $1 WHERE pruned_through < $1", through)
+		return err
+	})
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка очистки журнала изменений", "error", err)
+		return 0, err
+	}
+
+	repo.log.DebugContext(ctx, "успешно выполнен PruneChanges", "pruned", pruned)
+	return pruned, nil
+}
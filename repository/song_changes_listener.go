@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// songChangesChannel — канал NOTIFY, в который триггер таблицы songs
+// сообщает об изменении песен (миграция 007).
+const songChangesChannel = "song_changes"
+
+// Паузы между попытками восстановить соединение слушателя.
+const (
+	listenerRetryMin = time.Second
+	listenerRetryMax = time.Minute
+)
+
+// listenerPingInterval — как часто проверять соединение слушателя, если
+// уведомлений нет: разрыв обнаруживается и соединение восстанавливается.
+const listenerPingInterval = 90 * time.Second
+
+// ChangeListener ждет уведомлений PostgreSQL об изменении песен через
+// LISTEN/NOTIFY. Уведомления идут по отдельному соединению вне пула.
+type ChangeListener struct {
+	dsn string
+	log *slog.Logger
+}
+
+// NewChangeListener создает слушателя уведомлений для базы по адресу dsn.
+func NewChangeListener(dsn string, logger *slog.Logger) *ChangeListener {
+	return &ChangeListener{dsn: dsn, log: logger}
+}
+
+// Listen вызывает notify после фиксации каждой транзакции, изменившей
+// песни, пока не отменен ctx. Уведомления за время разрыва соединения
+// теряются, поэтому notify вызывается и после его восстановления.
+func (listener *ChangeListener) Listen(ctx context.Context, notify func()) error {
+	conn := pq.NewListener(listener.dsn, listenerRetryMin, listenerRetryMax, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			listener.log.WarnContext(ctx, "потеряно соединение для уведомлений об изменениях песен", "error", err)
+		case pq.ListenerEventConnectionAttemptFailed:
+			listener.log.WarnContext(ctx, "ошибка подключения для уведомлений об изменениях песен", "error", err)
+		case pq.ListenerEventReconnected:
+			listener.log.InfoContext(ctx, "соединение для уведомлений об изменениях песен восстановлено")
+		}
+	})
+	defer conn.Close()
+
+	if err := conn.Listen(songChangesChannel); err != nil {
+		return fmt.Errorf("ошибка подписки на канал %s: %w", songChangesChannel, err)
+	}
+
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-conn.Notify:
+			// nil приходит после восстановления соединения
+			notify()
+		case <-ping.C:
+			go conn.Ping()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
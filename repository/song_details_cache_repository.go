@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"song-library/domain"
+	"time"
+)
+
+// SongDetailsCacheRepository хранит ответы внешнего API в таблице song_details_cache.
+// Запросы совместимы с PostgreSQL и SQLite.
+type SongDetailsCacheRepository struct {
+	db  *sql.DB
+	log *slog.Logger
+}
+
+func NewSongDetailsCacheRepository(db *sql.DB, logger *slog.Logger) *SongDetailsCacheRepository {
+	return &SongDetailsCacheRepository{db: db, log: logger}
+}
+
+// GetDetails возвращает неустаревшую запись кэша или nil, если записи нет.
+func (repo *SongDetailsCacheRepository) GetDetails(ctx context.Context, key string) (*domain.SongDetailCacheEntry, error) {
+	var detail domain.SongDetail
+	var entry domain.SongDetailCacheEntry
+	err := repo.db.QueryRowContext(ctx,
+		"SELECT release_date, text, link, not_found, expires_at FROM song_details_cache WHERE cache_key = $1 AND expires_at > $2",
+		key, time.Now().UTC(),
+	).Scan(&detail.ReleaseDate, &detail.Text, &detail.Link, &entry.NotFound, &entry.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		repo.log.ErrorContext(ctx, "ошибка чтения кэша деталей песни", "key", key, "error", err)
+		return nil, err
+	}
+
+	if !entry.NotFound {
+		entry.Detail = &detail
+	}
+	return &entry, nil
+}
+
+// PutDetails сохраняет запись кэша, заменяя существующую.
+func (repo *SongDetailsCacheRepository) PutDetails(ctx context.Context, key string, entry domain.SongDetailCacheEntry) error {
+	var detail domain.SongDetail
+	if entry.Detail != nil {
+		detail = *entry.Detail
+	}
+
+	_, err := repo.db.ExecContext(ctx,
+		`INSERT INTO song_details_cache (cache_key, release_date, text, link, not_found, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (cache_key) DO UPDATE SET
+			release_date = EXCLUDED.release_date,
+			text = EXCLUDED.text,
+			link = EXCLUDED.link,
+			not_found = EXCLUDED.not_found,
+			expires_at = EXCLUDED.expires_at`,
+		key, detail.ReleaseDate, detail.Text, detail.Link, entry.NotFound, entry.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка записи кэша деталей песни", "key", key, "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -1,126 +1,419 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
+	"song-library/auth"
 	"song-library/domain"
+	"strings"
 )
 
+// SongRepository выполняет запросы к таблице songs в PostgreSQL или SQLite.
+// Репозиторий, полученный в InTx, выполняет все запросы в рамках своей транзакции.
 type SongRepository struct {
-	db  *sql.DB
-	log *log.Logger
+	db      *sql.DB
+	exec    executor
+	tx      *sql.Tx
+	dialect dialect
+	log     *slog.Logger
 }
 
-func NewSongRepository(db *sql.DB, logger *log.Logger) *SongRepository {
-	return &SongRepository{db: db, log: logger}
+// NewSongRepository создает репозиторий для PostgreSQL.
+func NewSongRepository(db *sql.DB, logger *slog.Logger) *SongRepository {
+	return &SongRepository{db: db, exec: traced(db, postgresDialect), dialect: postgresDialect, log: logger}
 }
 
-func (repo *SongRepository) GetSongs(offset, limit int) ([]domain.Song, error) {
-	rows, err := repo.db.Query("SELECT id, group_name, song_name, release_date, text, link FROM songs LIMIT $1 OFFSET $2", limit, offset)
+// NewSQLiteSongRepository создает репозиторий для SQLite.
+func NewSQLiteSongRepository(db *sql.DB, logger *slog.Logger) *SongRepository {
+	return &SongRepository{db: db, exec: traced(db, sqliteDialect), dialect: sqliteDialect, log: logger}
+}
+
+// songColumns перечисляет колонки песни в порядке полей scanSong.
+const songColumns = "id, group_name, song_name, release_date, text, link, created_at, updated_at, created_by, updated_by"
+
+// exportFetchSize задает количество строк, получаемых из курсора за один FETCH.
+const exportFetchSize = 500
+
+// GetSongs возвращает страницу песен, удовлетворяющих фильтру, в порядке ID.
+func (repo *SongRepository) GetSongs(ctx context.Context, filter domain.SongFilter, offset, limit int) ([]domain.Song, error) {
+	where, args := repo.songFilterClause(filter)
+	args = append(args, limit, offset)
+	query := fmt.Sprintf("SELECT %s FROM songs%s ORDER BY id LIMIT $%d OFFSET $%d", songColumns, where, len(args)-1, len(args))
+
+	rows, err := repo.exec.QueryContext(ctx, query, args...)
 	if err != nil {
-		repo.log.Printf("ошибка выполнения GetSongs: %v", err)
+		repo.log.ErrorContext(ctx, "ошибка выполнения GetSongs", "error", err)
 		return nil, err
 	}
 	defer func() {
 		if closeErr := rows.Close(); closeErr != nil {
-			repo.log.Printf("ошибка закрытия rows в GetSongs: %v", closeErr)
+			repo.log.ErrorContext(ctx, "ошибка закрытия rows в GetSongs", "error", closeErr)
 		}
 	}()
 
 	var songs []domain.Song
 	for rows.Next() {
-		var song domain.Song
-		if err := rows.Scan(&song.ID, &song.Group, &song.Song, &song.ReleaseDate, &song.Text, &song.Link); err != nil {
-			repo.log.Printf("ошибка сканирования строки в GetSongs: %v", err)
+		song, err := scanSong(rows)
+		if err != nil {
+			repo.log.ErrorContext(ctx, "ошибка сканирования строки в GetSongs", "error", err)
 			return nil, err
 		}
 		songs = append(songs, song)
 	}
 
 	if err := rows.Err(); err != nil {
-		repo.log.Printf("ошибка итерации строк в GetSongs: %v", err)
+		repo.log.ErrorContext(ctx, "ошибка итерации строк в GetSongs", "error", err)
 		return nil, err
 	}
 
-	repo.log.Printf("успешно выполнен GetSongs (offset=%d, limit=%d)", offset, limit)
+	repo.log.DebugContext(ctx, "успешно выполнен GetSongs", "offset", offset, "limit", limit)
 	return songs, nil
 }
 
-func (repo *SongRepository) AddSong(song domain.Song) error {
-	_, err := repo.db.Exec(
-		"INSERT INTO songs (group_name, song_name, release_date, text, link) VALUES ($1, $2, $3, $4, $5)",
-		song.Group, song.Song, song.ReleaseDate, song.Text, song.Link,
-	)
-	if err != nil {
-		repo.log.Printf("ошибка добавления песни: group=%s, song=%s, error=%v", song.Group, song.Song, err)
-		return err
+// StreamSongs читает песни, удовлетворяющие фильтру, через серверный курсор
+// в порядке ID и передает каждую в fn, не загружая всю таблицу в память.
+func (repo *SongRepository) StreamSongs(ctx context.Context, filter domain.SongFilter, fn func(domain.Song) error) error {
+	if !repo.dialect.cursors {
+		return repo.streamRows(ctx, filter, fn)
 	}
 
-	repo.log.Printf("песня успешно добавлена: group=%s, song=%s", song.Group, song.Song)
-	return nil
+	options := &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead}
+	return repo.withTx(ctx, options, func(tx *SongRepository) error {
+		return tx.streamCursor(ctx, filter, fn)
+	})
 }
 
-func (repo *SongRepository) UpdateSong(song domain.Song) error {
-	res, err := repo.db.Exec(
-		"UPDATE songs SET group_name = $1, song_name = $2, release_date = $3, text = $4, link = $5 WHERE id = $6",
-		song.Group, song.Song, song.ReleaseDate, song.Text, song.Link, song.ID,
-	)
-	if err != nil {
-		repo.log.Printf("ошибка обновления песни: id=%d, error=%v", song.ID, err)
+// streamCursor читает песни порциями через DECLARE CURSOR и FETCH.
+func (repo *SongRepository) streamCursor(ctx context.Context, filter domain.SongFilter, fn func(domain.Song) error) error {
+	where, args := repo.songFilterClause(filter)
+	declare := fmt.Sprintf("DECLARE songs_export NO SCROLL CURSOR FOR SELECT %s FROM songs%s ORDER BY id", songColumns, where)
+	if _, err := repo.exec.ExecContext(ctx, declare, args...); err != nil {
+		repo.log.ErrorContext(ctx, "ошибка объявления курсора в StreamSongs", "error", err)
 		return err
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	total := 0
+	for {
+		rows, err := repo.exec.QueryContext(ctx, fmt.Sprintf("FETCH FORWARD %d FROM songs_export", exportFetchSize))
+		if err != nil {
+			repo.log.ErrorContext(ctx, "ошибка чтения курсора в StreamSongs", "error", err)
+			return err
+		}
+
+		fetched := 0
+		for rows.Next() {
+			song, err := scanSong(rows)
+			if err != nil {
+				rows.Close()
+				repo.log.ErrorContext(ctx, "ошибка сканирования строки в StreamSongs", "error", err)
+				return err
+			}
+			fetched++
+			if err := fn(song); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			repo.log.ErrorContext(ctx, "ошибка итерации строк в StreamSongs", "error", err)
+			return err
+		}
+		if err := rows.Close(); err != nil {
+			repo.log.ErrorContext(ctx, "ошибка закрытия rows в StreamSongs", "error", err)
+			return err
+		}
+
+		total += fetched
+		if fetched < exportFetchSize {
+			break
+		}
+	}
+
+	if _, err := repo.exec.ExecContext(ctx, "CLOSE songs_export"); err != nil {
+		repo.log.ErrorContext(ctx, "ошибка закрытия курсора в StreamSongs", "error", err)
+		return err
+	}
+
+	repo.log.DebugContext(ctx, "успешно выполнен StreamSongs", "count", total)
+	return nil
+}
+
+// streamRows читает песни одним запросом, передавая строки в fn по мере чтения.
+func (repo *SongRepository) streamRows(ctx context.Context, filter domain.SongFilter, fn func(domain.Song) error) error {
+	where, args := repo.songFilterClause(filter)
+	rows, err := repo.exec.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM songs%s ORDER BY id", songColumns, where), args...)
 	if err != nil {
-		repo.log.Printf("ошибка получения количества затронутых строк в UpdateSong: %v", err)
+		repo.log.ErrorContext(ctx, "ошибка выполнения StreamSongs", "error", err)
 		return err
 	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			repo.log.ErrorContext(ctx, "ошибка закрытия rows в StreamSongs", "error", closeErr)
+		}
+	}()
 
-	if rowsAffected == 0 {
-		repo.log.Printf("песня для обновления не найдена: id=%d", song.ID)
-		return errors.New("песня не найдена")
+	total := 0
+	for rows.Next() {
+		song, err := scanSong(rows)
+		if err != nil {
+			repo.log.ErrorContext(ctx, "ошибка сканирования строки в StreamSongs", "error", err)
+			return err
+		}
+		if err := fn(song); err != nil {
+			return err
+		}
+		total++
+	}
+	if err := rows.Err(); err != nil {
+		repo.log.ErrorContext(ctx, "ошибка итерации строк в StreamSongs", "error", err)
+		return err
 	}
 
-	repo.log.Printf("песня успешно обновлена: id=%d", song.ID)
+	repo.log.DebugContext(ctx, "успешно выполнен StreamSongs", "count", total)
 	return nil
 }
 
-func (repo *SongRepository) DeleteSong(id int) error {
-	res, err := repo.db.Exec("DELETE FROM songs WHERE id = $1", id)
+// AddSong добавляет песню от имени пользователя из контекста и возвращает ее ID.
+// Песня и запись о ней в журнале изменений сохраняются в одной транзакции.
+func (repo *SongRepository) AddSong(ctx context.Context, song domain.Song) (int, error) {
+	var id int
+	err := repo.withTx(ctx, nil, func(tx *SongRepository) error {
+		err := tx.exec.QueryRowContext(ctx,
+			"INSERT INTO songs (group_name, song_name, release_date, text, link, created_by, updated_by) VALUES ($1, $2, $3, $4, $5, $6, $6) RETURNING id",
+			song.Group, song.Song, song.ReleaseDate, song.Text, song.Link, auth.User(ctx),
+		).Scan(&id)
+		if err != nil {
+			repo.log.ErrorContext(ctx, "ошибка добавления песни", "group", song.Group, "song", song.Song, "error", err)
+			return repo.translateError(err)
+		}
+		return tx.recordChanges(ctx, domain.ChangeCreated, domain.EventSongCreated, []int{id})
+	})
 	if err != nil {
-		repo.log.Printf("ошибка удаления песни: id=%d, error=%v", id, err)
-		return err
+		return 0, err
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	repo.log.DebugContext(ctx, "песня успешно добавлена", "song_id", id, "group", song.Group, "song", song.Song)
+	return id, nil
+}
+
+// UpdateSong заменяет данные песни от имени пользователя из контекста.
+// Время изменения обновляет триггер базы данных.
+func (repo *SongRepository) UpdateSong(ctx context.Context, song domain.Song) error {
+	return repo.updateSong(ctx, song, domain.EventSongUpdated)
+}
+
+// EnrichSong сохраняет данные песни, дополненные деталями из внешнего API.
+// В журнал изменений она попадает как обычное изменение, а подписчикам
+// уходит событие song.enriched.
+func (repo *SongRepository) EnrichSong(ctx context.Context, song domain.Song) error {
+	return repo.updateSong(ctx, song, domain.EventSongEnriched)
+}
+
+// updateSong заменяет данные песни и сообщает подписчикам событие event.
+func (repo *SongRepository) updateSong(ctx context.Context, song domain.Song, event string) error {
+	err := repo.withTx(ctx, nil, func(tx *SongRepository) error {
+		res, err := tx.exec.ExecContext(ctx,
+			"UPDATE songs SET group_name = $1, song_name = $2, release_date = $3, text = $4, link = $5, updated_by = $6 WHERE id = $7",
+			song.Group, song.Song, song.ReleaseDate, song.Text, song.Link, auth.User(ctx), song.ID,
+		)
+		if err != nil {
+			repo.log.ErrorContext(ctx, "ошибка обновления песни", "song_id", song.ID, "error", err)
+			return repo.translateError(err)
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			repo.log.ErrorContext(ctx, "ошибка получения количества затронутых строк в UpdateSong", "error", err)
+			return err
+		}
+
+		if rowsAffected == 0 {
+			repo.log.WarnContext(ctx, "песня для обновления не найдена", "song_id", song.ID)
+			return ErrSongNotFound
+		}
+		return tx.recordChanges(ctx, domain.ChangeUpdated, event, []int{song.ID})
+	})
 	if err != nil {
-		repo.log.Printf("ошибка получения количества затронутых строк в DeleteSong: %v", err)
 		return err
 	}
 
-	if rowsAffected == 0 {
-		repo.log.Printf("песня для удаления не найдена: id=%d", id)
-		return errors.New("песня не найдена")
+	repo.log.DebugContext(ctx, "песня успешно обновлена", "song_id", song.ID)
+	return nil
+}
+
+func (repo *SongRepository) DeleteSong(ctx context.Context, id int) error {
+	err := repo.withTx(ctx, nil, func(tx *SongRepository) error {
+		// Группа удаленной песни нужна журналу изменений
+		var group string
+		err := tx.exec.QueryRowContext(ctx, "DELETE FROM songs WHERE id = $1 RETURNING group_name", id).Scan(&group)
+		if errors.Is(err, sql.ErrNoRows) {
+			repo.log.WarnContext(ctx, "песня для удаления не найдена", "song_id", id)
+			return ErrSongNotFound
+		}
+		if err != nil {
+			repo.log.ErrorContext(ctx, "ошибка удаления песни", "song_id", id, "error", err)
+			return err
+		}
+		return tx.recordDeletion(ctx, id, group)
+	})
+	if err != nil {
+		return err
 	}
 
-	repo.log.Printf("песня успешно удалена: id=%d", id)
+	repo.log.DebugContext(ctx, "песня успешно удалена", "song_id", id)
 	return nil
 }
 
-func (repo *SongRepository) GetSongByID(id int) (*domain.Song, error) {
-	var song domain.Song
-	err := repo.db.QueryRow("SELECT id, group_name, song_name, release_date, text, link FROM songs WHERE id = $1", id).
-		Scan(&song.ID, &song.Group, &song.Song, &song.ReleaseDate, &song.Text, &song.Link)
+func (repo *SongRepository) GetSongByID(ctx context.Context, id int) (*domain.Song, error) {
+	song, err := scanSong(repo.exec.QueryRowContext(ctx, "SELECT "+songColumns+" FROM songs WHERE id = $1", id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			repo.log.Printf("песня не найдена: id=%d", id)
-			return nil, errors.New("песня не найдена")
+			repo.log.WarnContext(ctx, "песня не найдена", "song_id", id)
+			return nil, ErrSongNotFound
 		}
-		repo.log.Printf("ошибка получения песни по ID: id=%d, error=%v", id, err)
+		repo.log.ErrorContext(ctx, "ошибка получения песни по ID", "song_id", id, "error", err)
 		return nil, err
 	}
 
-	repo.log.Printf("песня успешно получена: id=%d", id)
+	repo.log.DebugContext(ctx, "песня успешно получена", "song_id", id)
 	return &song, nil
 }
+
+// CountSongs возвращает общее количество песен и количество песен, которым
+// не хватает деталей из внешнего API (см. domain.Song.NeedsEnrichment).
+func (repo *SongRepository) CountSongs(ctx context.Context) (domain.SongCounts, error) {
+	var counts domain.SongCounts
+	err := repo.exec.QueryRowContext(ctx,
+		"SELECT COUNT(*), COALESCE(SUM(CASE WHEN release_date = '' OR text = '' OR link = '' THEN 1 ELSE 0 END), 0) FROM songs",
+	).Scan(&counts.Total, &counts.PendingEnrichment)
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка подсчета песен", "error", err)
+		return domain.SongCounts{}, err
+	}
+	return counts, nil
+}
+
+// InsertSongs добавляет песни одним многострочным INSERT в транзакции и возвращает
+// для каждой песни признак того, что она была добавлена. Песни, уже существующие
+// в библиотеке, пропускаются. При dryRun транзакция откатывается.
+func (repo *SongRepository) InsertSongs(ctx context.Context, songs []domain.Song, dryRun bool) ([]bool, error) {
+	created := make([]bool, len(songs))
+	if len(songs) == 0 {
+		return created, nil
+	}
+
+	var query strings.Builder
+	query.WriteString("INSERT INTO songs (group_name, song_name, release_date, text, link, created_by, updated_by) VALUES ")
+	args := make([]interface{}, 0, len(songs)*5+1)
+	args = append(args, auth.User(ctx))
+	for i, song := range songs {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		n := i*5 + 1
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $1, $1)", n+1, n+2, n+3, n+4, n+5)
+		args = append(args, song.Group, song.Song, song.ReleaseDate, song.Text, song.Link)
+	}
+	query.WriteString(" ON CONFLICT (group_name, song_name) DO NOTHING RETURNING id, group_name, song_name")
+
+	err := repo.withTx(ctx, nil, func(tx *SongRepository) error {
+		rows, err := tx.exec.QueryContext(ctx, query.String(), args...)
+		if err != nil {
+			repo.log.ErrorContext(ctx, "ошибка выполнения InsertSongs", "count", len(songs), "error", err)
+			return err
+		}
+		defer rows.Close()
+
+		inserted := make(map[[2]string]int)
+		var ids []int
+		for rows.Next() {
+			var id int
+			var key [2]string
+			if err := rows.Scan(&id, &key[0], &key[1]); err != nil {
+				repo.log.ErrorContext(ctx, "ошибка сканирования строки в InsertSongs", "error", err)
+				return err
+			}
+			inserted[key]++
+			ids = append(ids, id)
+		}
+		if err := rows.Err(); err != nil {
+			repo.log.ErrorContext(ctx, "ошибка итерации строк в InsertSongs", "error", err)
+			return err
+		}
+
+		for i, song := range songs {
+			key := [2]string{song.Group, song.Song}
+			if inserted[key] > 0 {
+				inserted[key]--
+				created[i] = true
+			}
+		}
+
+		if dryRun {
+			return errRollback
+		}
+		return tx.recordChanges(ctx, domain.ChangeCreated, domain.EventSongCreated, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		repo.log.DebugContext(ctx, "пробный InsertSongs выполнен, изменения откачены", "count", len(songs))
+		return created, nil
+	}
+
+	repo.log.DebugContext(ctx, "успешно выполнен InsertSongs", "count", len(songs))
+	return created, nil
+}
+
+// rowScanner объединяет *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSong читает песню из строки, выбранной с колонками songColumns.
+func scanSong(row rowScanner) (domain.Song, error) {
+	var song domain.Song
+	err := row.Scan(&song.ID, &song.Group, &song.Song, &song.ReleaseDate, &song.Text, &song.Link,
+		&song.CreatedAt, &song.UpdatedAt, &song.CreatedBy, &song.UpdatedBy)
+	return song, err
+}
+
+// songFilterClause строит условие WHERE для фильтра и его параметры.
+func (repo *SongRepository) songFilterClause(filter domain.SongFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if filter.Group != "" {
+		args = append(args, filter.Group)
+		conditions = append(conditions, repo.dialect.contains("group_name", len(args)))
+	}
+	if filter.Song != "" {
+		args = append(args, filter.Song)
+		conditions = append(conditions, repo.dialect.contains("song_name", len(args)))
+	}
+	if filter.ReleaseDate != "" {
+		args = append(args, filter.ReleaseDate)
+		conditions = append(conditions, fmt.Sprintf("release_date = $%d", len(args)))
+	}
+	if !filter.UpdatedSince.IsZero() {
+		args = append(args, filter.UpdatedSince)
+		conditions = append(conditions, repo.dialect.after("updated_at", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// translateError заменяет нарушение уникальности пары группа/название на ErrSongExists.
+func (repo *SongRepository) translateError(err error) error {
+	if repo.dialect.isUniqueViolation(err) {
+		return ErrSongExists
+	}
+	return err
+}
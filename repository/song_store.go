@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"song-library/domain"
+	"time"
+)
+
+// ErrSongNotFound возвращается, когда песни с указанным ID нет в хранилище.
+var ErrSongNotFound = domain.NewNotFoundError(domain.CodeSongNotFound, "песня не найдена")
+
+// ErrSongExists возвращается при попытке сохранить вторую песню с той же парой группа/название.
+var ErrSongExists = domain.NewConflictError(domain.CodeSongExists, "песня с такой группой и названием уже существует")
+
+// SongStore описывает хранилище песен. Реализации: SongRepository для
+// PostgreSQL (NewSongRepository) и SQLite (NewSQLiteSongRepository) и
+// MemorySongStore (в памяти, для тестов и демонстрации).
+type SongStore interface {
+	// GetSongs возвращает страницу песен, удовлетворяющих фильтру, в порядке ID.
+	GetSongs(ctx context.Context, filter domain.SongFilter, offset, limit int) ([]domain.Song, error)
+	// StreamSongs передает в fn все песни, удовлетворяющие фильтру, в порядке ID.
+	StreamSongs(ctx context.Context, filter domain.SongFilter, fn func(domain.Song) error) error
+	// GetSongByID возвращает песню или ErrSongNotFound.
+	GetSongByID(ctx context.Context, id int) (*domain.Song, error)
+	// AddSong добавляет песню и возвращает ее ID.
+	AddSong(ctx context.Context, song domain.Song) (int, error)
+	// UpdateSong заменяет данные песни с ID song.ID или возвращает ErrSongNotFound.
+	UpdateSong(ctx context.Context, song domain.Song) error
+	// EnrichSong сохраняет данные песни, дополненные деталями из внешнего API,
+	// или возвращает ErrSongNotFound. Отличается от UpdateSong событием для вебхуков.
+	EnrichSong(ctx context.Context, song domain.Song) error
+	// DeleteSong удаляет песню или возвращает ErrSongNotFound.
+	DeleteSong(ctx context.Context, id int) error
+	// CountSongs возвращает общее количество песен и количество песен без деталей.
+	CountSongs(ctx context.Context) (domain.SongCounts, error)
+	// InsertSongs добавляет песни, пропуская существующие, и возвращает признак добавления каждой.
+	InsertSongs(ctx context.Context, songs []domain.Song, dryRun bool) ([]bool, error)
+	// GetChanges возвращает состояние журнала изменений и до limit изменений
+	// с номерами больше after в порядке фиксации.
+	GetChanges(ctx context.Context, after int64, limit int) (domain.ChangeLogState, []domain.SongChange, error)
+	// PruneChanges удаляет из журнала изменения, сделанные раньше before, и возвращает их количество.
+	PruneChanges(ctx context.Context, before time.Time) (int64, error)
+	// InTx выполняет fn в транзакции: ошибка fn откатывает все изменения.
+	InTx(ctx context.Context, fn func(tx SongStore) error) error
+	// Savepoint внутри транзакции откатывает только изменения fn, если она вернула ошибку.
+	Savepoint(ctx context.Context, name string, fn func() error) error
+}
+
+var (
+	_ SongStore = (*SongRepository)(nil)
+	_ SongStore = (*MemorySongStore)(nil)
+)
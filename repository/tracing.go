@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"song-library/tracing"
+)
+
+var tracer = otel.Tracer("song-library/repository")
+
+// tracedExecutor создает спан OpenTelemetry для каждого SQL-запроса.
+type tracedExecutor struct {
+	exec    executor
+	dialect dialect
+}
+
+func traced(exec executor, dialect dialect) executor {
+	return tracedExecutor{exec: exec, dialect: dialect}
+}
+
+func (e tracedExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := e.start(ctx, query)
+	result, err := e.exec.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (e tracedExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := e.start(ctx, query)
+	rows, err := e.exec.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+func (e tracedExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := e.start(ctx, query)
+	row := e.exec.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+// start открывает спан запроса с именем операции (SELECT, INSERT и т. д.) и
+// текстом запроса без значений параметров.
+func (e tracedExecutor) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	operation = strings.ToUpper(operation)
+	return tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(e.dialect.system, semconv.DBOperationName(operation), semconv.DBQueryText(query)),
+	)
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// executor объединяет методы *sql.DB и *sql.Tx, через которые репозиторий выполняет запросы.
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// errRollback прерывает транзакцию без ошибки для вызывающего кода.
+var errRollback = errors.New("откат транзакции")
+
+// InTx выполняет fn с репозиторием, привязанным к транзакции. Если fn возвращает
+// ошибку, транзакция откатывается, иначе фиксируется. Внутри уже открытой
+// транзакции fn выполняется в ней же.
+func (repo *SongRepository) InTx(ctx context.Context, fn func(tx SongStore) error) error {
+	return repo.withTx(ctx, nil, func(tx *SongRepository) error { return fn(tx) })
+}
+
+// Savepoint выполняет fn внутри точки сохранения текущей транзакции: при ошибке
+// откатываются только изменения fn, и транзакция остается пригодной для работы.
+func (repo *SongRepository) Savepoint(ctx context.Context, name string, fn func() error) error {
+	if repo.tx == nil {
+		return savepointOutsideTxError(name)
+	}
+
+	if _, err := repo.exec.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		repo.log.ErrorContext(ctx, "ошибка создания точки сохранения", "savepoint", name, "error", err)
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if _, rollbackErr := repo.exec.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rollbackErr != nil {
+			repo.log.ErrorContext(ctx, "ошибка отката к точке сохранения", "savepoint", name, "error", rollbackErr)
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+
+	if _, err := repo.exec.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		repo.log.ErrorContext(ctx, "ошибка освобождения точки сохранения", "savepoint", name, "error", err)
+		return err
+	}
+	return nil
+}
+
+// withTx открывает транзакцию с параметрами opts и выполняет в ней fn.
+// Ошибка errRollback откатывает транзакцию и не возвращается вызывающему.
+func (repo *SongRepository) withTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *SongRepository) error) error {
+	if repo.tx != nil {
+		return fn(repo)
+	}
+
+	tx, err := repo.db.BeginTx(ctx, opts)
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка открытия транзакции", "error", err)
+		return err
+	}
+
+	txRepo := &SongRepository{db: repo.db, exec: traced(tx, repo.dialect), tx: tx, dialect: repo.dialect, log: repo.log}
+	if err := fn(txRepo); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			repo.log.ErrorContext(ctx, "ошибка отката транзакции", "error", rollbackErr)
+		}
+		if errors.Is(err, errRollback) {
+			return nil
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		repo.log.ErrorContext(ctx, "ошибка фиксации транзакции", "error", err)
+		return err
+	}
+	return nil
+}
+
+func savepointOutsideTxError(name string) error {
+	return fmt.Errorf("точка сохранения %s доступна только внутри транзакции", name)
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"song-library/domain"
+	"time"
+)
+
+// ErrWebhookNotFound возвращается, когда подписки с указанным ID нет.
+var ErrWebhookNotFound = domain.NewNotFoundError(domain.CodeWebhookNotFound, "вебхук не найден")
+
+// ErrDeliveryNotFound возвращается, когда у подписки нет доставки с указанным ID.
+var ErrDeliveryNotFound = domain.NewNotFoundError(domain.CodeDeliveryNotFound, "доставка не найдена")
+
+// WebhookStore описывает хранилище подписок на события песен и очередь их
+// доставок. Доставки добавляются в очередь самим хранилищем песен в
+// транзакции изменения песни, поэтому WebhookStore реализуют те же типы,
+// что и SongStore.
+type WebhookStore interface {
+	// CreateWebhook сохраняет подписку и возвращает ее с ID и временем создания.
+	CreateWebhook(ctx context.Context, webhook domain.Webhook) (domain.Webhook, error)
+	// GetWebhooks возвращает все подписки в порядке ID.
+	GetWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	// GetWebhook возвращает подписку или ErrWebhookNotFound.
+	GetWebhook(ctx context.Context, id int) (*domain.Webhook, error)
+	// DeleteWebhook удаляет подписку вместе с ее доставками или возвращает ErrWebhookNotFound.
+	DeleteWebhook(ctx context.Context, id int) error
+	// GetDeliveries возвращает до limit доставок подписки с ID меньше before
+	// (0 — без ограничения) от новых к старым. Пустой status — в любом состоянии.
+	GetDeliveries(ctx context.Context, webhookID int, status string, before int64, limit int) ([]domain.WebhookDelivery, error)
+	// ClaimDeliveries выбирает до limit доставок, время попытки которых
+	// наступило к now, и откладывает их следующую попытку до now+lease, чтобы
+	// их не взял другой экземпляр приложения.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error)
+	// CompleteDelivery записывает результат попытки доставки.
+	CompleteDelivery(ctx context.Context, attempt domain.DeliveryAttempt) error
+	// Redeliver ставит доставку подписки в очередь заново со сброшенным
+	// счетчиком попыток или возвращает ErrDeliveryNotFound.
+	Redeliver(ctx context.Context, webhookID int, deliveryID int64) (*domain.WebhookDelivery, error)
+	// PruneDeliveries удаляет завершенные доставки, созданные раньше before,
+	// и возвращает их количество.
+	PruneDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Store объединяет хранилище песен и подписок на их события.
+type Store interface {
+	SongStore
+	WebhookStore
+}
+
+var (
+	_ Store = (*SongRepository)(nil)
+	_ Store = (*MemorySongStore)(nil)
+)
@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"song-library/auth"
+	"song-library/domain"
+)
+
+// deliveryColumns перечисляет колонки доставки в порядке полей scanDelivery.
+const deliveryColumns = "id, webhook_id, event, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, last_error, created_at, delivered_at"
+
+// webhookDeliveriesPerInsert ограничивает число доставок в одном INSERT.
+// Пакет импорта из тысяч песен на несколько вебхуков иначе превышает лимит
+// параметров PostgreSQL (65535) и SQLite (32766); небольшие части к тому же
+// быстрее связываются драйвером SQLite, время которого растет квадратично.
+const webhookDeliveriesPerInsert = 200
+
+// enqueueWebhookEvents ставит в очередь доставку события event по каждому из
+// изменений changes всем подписанным на него вебхукам.
+func (repo *SongRepository) enqueueWebhookEvents(ctx context.Context, event string, changes []domain.SongChange) error {
+	webhooks, err := repo.GetWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	var args []interface{}
+	occurredAt := time.Now().UTC()
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(event) {
+			continue
+		}
+		for _, change := range changes {
+			payload, err := webhookPayload(event, change, occurredAt)
+			if err != nil {
+				return err
+			}
+			args = append(args, webhook.ID, event, payload)
+			if len(args) == 3*webhookDeliveriesPerInsert {
+				if err := repo.insertWebhookDeliveries(ctx, event, args); err != nil {
+					return err
+				}
+				args = args[:0]
+			}
+		}
+	}
+	return repo.insertWebhookDeliveries(ctx, event, args)
+}
+
+// insertWebhookDeliveries добавляет в очередь доставки, заданные тройками
+// webhook_id, event, payload в args.
+func (repo *SongRepository) insertWebhookDeliveries(ctx context.Context, event string, args []interface{}) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	var query strings.Builder
+	query.WriteString("INSERT INTO webhook_deliveries (webhook_id, event, payload) VALUES ")
+	for n := 0; n < len(args); n += 3 {
+		if n > 0 {
+			query.WriteString(", ")
+		}
+		fmt.Fprintf(&query, "($%d, $%d, $%d)", n+1, n+2, n+3)
+	}
+
+	if _, err := repo.exec.ExecContext(ctx, query.String(), args...); err != nil {
+		repo.log.ErrorContext(ctx, "ошибка постановки событий в очередь вебхуков", "event", event, "error", err)
+		return err
+	}
+	return nil
+}
+
+// webhookPayload составляет тело запроса с событием event по изменению change.
+func webhookPayload(event string, change domain.SongChange, occurredAt time.Time) (string, error) {
+	payload := domain.WebhookEvent{Event: event, SongID: change.ID, Song: change.Song, OccurredAt: occurredAt}
+	data, err := json.Marshal(payload)
+	return string(data), err
+}
+
+// CreateWebhook сохраняет подписку от имени пользователя из контекста.
+func (repo *SongRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) (domain.Webhook, error) {
+	webhook.CreatedBy = auth.User(ctx)
+	err := repo.exec.QueryRowContext(ctx,
+		"INSERT INTO webhooks (url, events, secret, created_by) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		webhook.URL, strings.Join(webhook.Events, ","), webhook.Secret, webhook.CreatedBy,
+	).Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка создания вебхука", "url", webhook.URL, "error", err)
+		return domain.Webhook{}, err
+	}
+
+	repo.log.DebugContext(ctx, "вебхук создан", "webhook_id", webhook.ID)
+	return webhook, nil
+}
+
+func (repo *SongRepository) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	rows, err := repo.exec.QueryContext(ctx, "SELECT id, url, events, secret, created_at, created_by FROM webhooks ORDER BY id")
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка получения вебхуков", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []domain.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			repo.log.ErrorContext(ctx, "ошибка сканирования строки в GetWebhooks", "error", err)
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		repo.log.ErrorContext(ctx, "ошибка итерации строк в GetWebhooks", "error", err)
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (repo *SongRepository) GetWebhook(ctx context.Context, id int) (*domain.Webhook, error) {
+	webhook, err := scanWebhook(repo.exec.QueryRowContext(ctx,
+		"SELECT id, url, events, secret, created_at, created_by FROM webhooks WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		repo.log.ErrorContext(ctx, "ошибка получения вебхука", "webhook_id", id, "error", err)
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook удаляет подписку и ее доставки. Доставки удаляются явно:
+// SQLite по умолчанию не выполняет ON DELETE CASCADE.
+func (repo *SongRepository) DeleteWebhook(ctx context.Context, id int) error {
+	err := repo.withTx(ctx, nil, func(tx *SongRepository) error {
+		if _, err := tx.exec.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = $1", id); err != nil {
+			return err
+		}
+		res, err := tx.exec.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrWebhookNotFound) {
+			repo.log.ErrorContext(ctx, "ошибка удаления вебхука", "webhook_id", id, "error", err)
+		}
+		return err
+	}
+
+	repo.log.DebugContext(ctx, "вебхук удален", "webhook_id", id)
+	return nil
+}
+
+func (repo *SongRepository) GetDeliveries(ctx context.Context, webhookID int, status string, before int64, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	err := repo.withTx(ctx, repo.dialect.snapshot, func(tx *SongRepository) error {
+		if _, err := tx.GetWebhook(ctx, webhookID); err != nil {
+			return err
+		}
+
+		conditions := []string{"webhook_id = $1"}
+		args := []interface{}{webhookID}
+		if status != "" {
+			args = append(args, status)
+			conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+		}
+		if before > 0 {
+			args = append(args, before)
+			conditions = append(conditions, fmt.Sprintf("id < $%d", len(args)))
+		}
+		args = append(args, limit)
+		query := fmt.Sprintf("SELECT %s FROM webhook_deliveries WHERE %s ORDER BY id DESC LIMIT $%d",
+			deliveryColumns, strings.Join(conditions, " AND "), len(args))
+
+		var err error
+		deliveries, err = tx.queryDeliveries(ctx, query, args...)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, ErrWebhookNotFound) {
+			repo.log.ErrorContext(ctx, "ошибка получения журнала доставок", "webhook_id", webhookID, "error", err)
+		}
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimDeliveries откладывает выбранные доставки одним UPDATE: в PostgreSQL
+// повторная проверка условия не даст двум экземплярам взять одну доставку.
+func (repo *SongRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error) {
+	due := "status = 'pending' AND " + repo.dialect.before("next_attempt_at", 2)
+	query := fmt.Sprintf(
+		"UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id IN (SELECT id FROM webhook_deliveries WHERE %s ORDER BY id LIMIT $3) AND %s RETURNING %s",
+		due, due, deliveryColumns)
+	deliveries, err := repo.queryDeliveries(ctx, query, now.Add(lease), now, limit)
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка выбора доставок из очереди", "error", err)
+		return nil, err
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID < deliveries[j].ID })
+	return deliveries, nil
+}
+
+func (repo *SongRepository) CompleteDelivery(ctx context.Context, attempt domain.DeliveryAttempt) error {
+	status, next, deliveredAt := deliveryOutcome(attempt)
+	_, err := repo.exec.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = $2, attempts = attempts + 1, last_attempt_at = $3,
+			response_status = $4, last_error = $5, next_attempt_at = $6, delivered_at = $7 WHERE id = $1`,
+		attempt.DeliveryID, status, attempt.At, attempt.ResponseStatus, attempt.Error, next, deliveredAt,
+	)
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка записи результата доставки", "delivery_id", attempt.DeliveryID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (repo *SongRepository) Redeliver(ctx context.Context, webhookID int, deliveryID int64) (*domain.WebhookDelivery, error) {
+	deliveries, err := repo.queryDeliveries(ctx,
+		"UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = $3, delivered_at = NULL WHERE id = $1 AND webhook_id = $2 RETURNING "+deliveryColumns,
+		deliveryID, webhookID, time.Now().UTC(),
+	)
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка повторной постановки доставки в очередь", "delivery_id", deliveryID, "error", err)
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, ErrDeliveryNotFound
+	}
+	return &deliveries[0], nil
+}
+
+func (repo *SongRepository) PruneDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	res, err := repo.exec.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE status <> 'pending' AND "+repo.dialect.before("created_at", 1), before)
+	if err != nil {
+		repo.log.ErrorContext(ctx, "ошибка очистки журнала доставок", "error", err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// queryDeliveries выполняет запрос, выбирающий колонки deliveryColumns.
+func (repo *SongRepository) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]domain.WebhookDelivery, error) {
+	rows, err := repo.exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// deliveryOutcome возвращает состояние доставки после попытки attempt, время
+// следующей попытки и время успешной доставки (nil, если ее не было).
+func deliveryOutcome(attempt domain.DeliveryAttempt) (status string, next time.Time, deliveredAt *time.Time) {
+	switch {
+	case attempt.Delivered:
+		return domain.DeliveryDelivered, attempt.At, &attempt.At
+	case attempt.NextAttemptAt.IsZero():
+		return domain.DeliveryFailed, attempt.At, nil
+	default:
+		return domain.DeliveryPending, attempt.NextAttemptAt, nil
+	}
+}
+
+// scanWebhook читает подписку из строки; события хранятся через запятую.
+func scanWebhook(row rowScanner) (domain.Webhook, error) {
+	var webhook domain.Webhook
+	var events string
+	err := row.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Secret, &webhook.CreatedAt, &webhook.CreatedBy)
+	webhook.Events = strings.Split(events, ",")
+	return webhook, err
+}
+
+// scanDelivery читает доставку из строки, выбранной с колонками deliveryColumns.
+func scanDelivery(row rowScanner) (domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	var payload string
+	var next time.Time
+	var lastAttemptAt, deliveredAt sql.NullTime
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &payload, &delivery.Status, &delivery.Attempts,
+		&next, &lastAttemptAt, &delivery.ResponseStatus, &delivery.LastError, &delivery.CreatedAt, &deliveredAt)
+	if err != nil {
+		return delivery, err
+	}
+
+	delivery.Payload = json.RawMessage(payload)
+	if delivery.Status == domain.DeliveryPending {
+		delivery.NextAttemptAt = &next
+	}
+	if lastAttemptAt.Valid {
+		delivery.LastAttemptAt = &lastAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return delivery, nil
+}
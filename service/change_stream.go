@@ -0,0 +1,402 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"song-library/domain"
+	"song-library/repository"
+)
+
+// Размеры пачек потока событий.
+const (
+	streamPageSize   = 500 // Изменений за одно чтение журнала
+	subscriberBuffer = 256 // Изменений в очереди подписчика; отставший подписчик дочитывает журнал сам
+)
+
+// streamResync — как часто поток, получающий уведомления, все равно читает
+// журнал: страховка от уведомлений, потерянных при разрыве соединения.
+const streamResync = time.Minute
+
+// ErrStreamClosed возвращается подписчикам при остановке приложения.
+var ErrStreamClosed = errors.New("поток событий остановлен")
+
+// ChangeNotifier сообщает о фиксации изменений песен, например через
+// LISTEN/NOTIFY PostgreSQL.
+type ChangeNotifier interface {
+	// Listen вызывает notify после фиксации изменений, пока не отменен ctx.
+	Listen(ctx context.Context, notify func()) error
+}
+
+// ChangeStream рассылает изменения песен из журнала изменений подписчикам
+// потока событий. Журнал читается одним обработчиком для всех подписчиков:
+// по уведомлению notifier или, без него, каждые pollInterval.
+type ChangeStream struct {
+	repo         repository.SongStore
+	notifier     ChangeNotifier
+	log          *slog.Logger
+	pollInterval time.Duration
+
+	ready chan struct{} // Закрывается после первого чтения журнала
+	done  chan struct{} // Закрывается при остановке потока
+
+	mu sync.Mutex
+	// state — состояние журнала при последнем чтении; Latest — номер
+	// последнего разосланного изменения
+	state       domain.ChangeLogState
+	subscribers map[*ChangeSubscription]struct{}
+	closed      bool
+}
+
+// NewChangeStream создает новый ChangeStream. notifier равен nil, если
+// хранилище не сообщает об изменениях (SQLite, хранилище в памяти).
+func NewChangeStream(repo repository.SongStore, notifier ChangeNotifier, logger *slog.Logger, pollInterval time.Duration) *ChangeStream {
+	return &ChangeStream{
+		repo:         repo,
+		notifier:     notifier,
+		log:          logger,
+		pollInterval: pollInterval,
+		ready:        make(chan struct{}),
+		done:         make(chan struct{}),
+		subscribers:  make(map[*ChangeSubscription]struct{}),
+	}
+}
+
+// Run читает журнал изменений и рассылает новые изменения подписчикам,
+// пока не отменен ctx, после чего завершает подписки с ErrStreamClosed.
+func (stream *ChangeStream) Run(ctx context.Context) {
+	defer stream.Close()
+
+	wake := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+
+	interval := stream.pollInterval
+	if stream.notifier != nil {
+		interval = streamResync
+		go func() {
+			err := stream.notifier.Listen(ctx, notify)
+			if err == nil {
+				return
+			}
+			// Без уведомлений поток продолжает работать, читая журнал по таймеру
+			stream.log.ErrorContext(ctx, "уведомления об изменениях песен недоступны", "poll_interval", stream.pollInterval, "error", err)
+			ticker := time.NewTicker(stream.pollInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					notify()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stream.readLog(ctx)
+		select {
+		case <-wake:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// readLog рассылает изменения, добавленные в журнал после последнего
+// разосланного.
+func (stream *ChangeStream) readLog(ctx context.Context) {
+	for ctx.Err() == nil {
+		// state меняет только этот обработчик, поэтому читается без блокировки
+		state, changes, err := stream.repo.GetChanges(ctx, stream.state.Latest, streamPageSize)
+		if err != nil {
+			stream.log.ErrorContext(ctx, "ошибка чтения журнала изменений для потока событий", "after", stream.state.Latest, "error", err)
+			return
+		}
+		if stream.publish(ctx, state, changes) < streamPageSize {
+			return
+		}
+	}
+}
+
+// publish рассылает прочитанные из журнала изменения подписчикам и
+// возвращает их количество.
+func (stream *ChangeStream) publish(ctx context.Context, state domain.ChangeLogState, changes []domain.SongChange) int {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	switch {
+	case stream.state.LogID == "":
+		// Первое чтение: подписчики получают изменения с текущего момента
+		stream.state = state
+		close(stream.ready)
+		return 0
+	case state.LogID != stream.state.LogID || stream.state.Latest < state.PrunedThrough:
+		// Журнал заменен (например, при восстановлении базы) или изменения
+		// удалены раньше, чем их разослали: подписчикам нужно загрузить
+		// библиотеку заново
+		stream.log.WarnContext(ctx, "журнал изменений заменен, подписки потока событий сброшены", "subscribers", len(stream.subscribers))
+		for sub := range stream.subscribers {
+			stream.unsubscribe(sub, ErrSyncTokenExpired)
+		}
+		stream.state = state
+		return 0
+	}
+
+	for _, change := range changes {
+		for sub := range stream.subscribers {
+			if !sub.offer(change) {
+				// Очередь подписчика переполнена: недостающие изменения он
+				// дочитает из журнала и подпишется снова
+				stream.unsubscribe(sub, nil)
+			}
+		}
+		stream.state.Latest = change.Seq
+	}
+	stream.state.PrunedThrough = state.PrunedThrough
+	return len(changes)
+}
+
+// Subscribe подписывает на изменения песен групп groups (без групп — всех
+// песен) после токена since. Пустой since означает "с текущего момента".
+// Для токена, изменения после которого уже недоступны, возвращается
+// ErrSyncTokenExpired: нужно загрузить библиотеку заново и подписаться без токена.
+func (stream *ChangeStream) Subscribe(ctx context.Context, since string, groups []string) (*ChangeSubscription, error) {
+	select {
+	case <-stream.ready:
+	case <-stream.done:
+		return nil, ErrStreamClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var logID string
+	var after int64
+	if since == "" {
+		// Рассылка может отставать от журнала, поэтому "текущий момент"
+		// определяется по самому журналу
+		state, _, err := stream.repo.GetChanges(ctx, 0, 0)
+		if err != nil {
+			stream.log.ErrorContext(ctx, "ошибка чтения журнала изменений для подписки", "error", err)
+			return nil, err
+		}
+		logID, after = state.LogID, state.Latest
+	} else {
+		var ok bool
+		if logID, after, ok = parseSyncToken(since); !ok {
+			err := domain.NewValidationError("last_event_id", "sync_token")
+			stream.log.WarnContext(ctx, "ошибка в Subscribe", "since", since, "error", err)
+			return nil, err
+		}
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if stream.closed {
+		return nil, ErrStreamClosed
+	}
+
+	state := stream.state
+	if since != "" && (logID != state.LogID || after < state.PrunedThrough) {
+		stream.log.InfoContext(ctx, "токен потока событий устарел", "since", since, "pruned_through", state.PrunedThrough, "latest", state.Latest)
+		return nil, ErrSyncTokenExpired
+	}
+
+	sub := &ChangeSubscription{stream: stream, groups: groups, logID: logID, after: after}
+	stream.subscribe(sub)
+	stream.log.DebugContext(ctx, "подписка на поток событий", "after", after, "groups", groups, "subscribers", len(stream.subscribers))
+	return sub, nil
+}
+
+// subscribe добавляет подписчика к рассылке. Изменения, разосланные до
+// этого, подписчик читает из журнала. Вызывается под stream.mu.
+func (stream *ChangeStream) subscribe(sub *ChangeSubscription) {
+	sub.until = stream.state.Latest
+	sub.live = make(chan domain.SongChange, subscriberBuffer)
+	sub.err = nil
+	stream.subscribers[sub] = struct{}{}
+}
+
+// unsubscribe исключает подписчика из рассылки с причиной err (nil —
+// подписчик отстал и может подписаться снова). Вызывается под stream.mu.
+func (stream *ChangeStream) unsubscribe(sub *ChangeSubscription, err error) {
+	if _, ok := stream.subscribers[sub]; !ok {
+		return
+	}
+	delete(stream.subscribers, sub)
+	sub.err = err
+	close(sub.live)
+}
+
+// Close завершает подписки с ErrStreamClosed и перестает принимать новые.
+// Вызывается при остановке сервера, чтобы открытые потоки не задерживали ее.
+func (stream *ChangeStream) Close() {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if stream.closed {
+		return
+	}
+	stream.closed = true
+	close(stream.done)
+	for sub := range stream.subscribers {
+		stream.unsubscribe(sub, ErrStreamClosed)
+	}
+}
+
+// ChangeSubscription — подписка на изменения песен. Методы подписки
+// вызываются из одной горутины.
+type ChangeSubscription struct {
+	stream *ChangeStream
+	groups []string
+	logID  string
+	after  int64 // Номер последнего полученного изменения
+
+	// Изменения с номерами до until включительно читаются из журнала,
+	// более поздние приходят через live
+	until   int64
+	backlog []domain.SongChange
+	live    chan domain.SongChange
+	err     error // Причина закрытия live; записывается до закрытия
+}
+
+// Next возвращает следующее изменение песен, ожидая его не дольше wait; nil
+// без ошибки означает, что изменений за это время не было.
+// ErrSyncTokenExpired означает, что часть изменений уже недоступна и
+// подписчику нужно загрузить библиотеку заново.
+func (sub *ChangeSubscription) Next(ctx context.Context, wait time.Duration) (*domain.SongChange, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		for sub.after < sub.until {
+			if len(sub.backlog) == 0 {
+				if err := sub.readBacklog(ctx); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			change := sub.backlog[0]
+			sub.backlog = sub.backlog[1:]
+			sub.after = change.Seq
+			if sub.matches(change) {
+				return &change, nil
+			}
+		}
+
+		select {
+		case change, ok := <-sub.live:
+			if ok {
+				// Токен /changes может опережать рассылку: полученное пропускается
+				if change.Seq <= sub.after {
+					continue
+				}
+				sub.after = change.Seq
+				if sub.matches(change) {
+					return &change, nil
+				}
+				continue
+			}
+			if err := sub.resubscribe(); err != nil {
+				return nil, err
+			}
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// readBacklog читает из журнала очередную пачку изменений, разосланных до
+// подписки.
+func (sub *ChangeSubscription) readBacklog(ctx context.Context) error {
+	state, changes, err := sub.stream.repo.GetChanges(ctx, sub.after, streamPageSize)
+	if err != nil {
+		sub.stream.log.ErrorContext(ctx, "ошибка чтения журнала изменений для подписчика", "after", sub.after, "error", err)
+		return err
+	}
+	if state.LogID != sub.logID || sub.after < state.PrunedThrough {
+		return ErrSyncTokenExpired
+	}
+
+	// Изменения после until придут через live
+	for i, change := range changes {
+		if change.Seq > sub.until {
+			changes = changes[:i]
+			break
+		}
+	}
+	if len(changes) == 0 {
+		// Номера без изменений остаются от откаченных транзакций
+		sub.after = sub.until
+	}
+	sub.backlog = changes
+	return nil
+}
+
+// resubscribe возвращает в рассылку подписчика, исключенного из нее, или
+// возвращает причину исключения.
+func (sub *ChangeSubscription) resubscribe() error {
+	if sub.err != nil {
+		return sub.err
+	}
+	sub.stream.mu.Lock()
+	defer sub.stream.mu.Unlock()
+	if sub.stream.closed {
+		return ErrStreamClosed
+	}
+	sub.stream.subscribe(sub)
+	return nil
+}
+
+// offer передает подписчику изменение из рассылки и сообщает false при
+// переполнении очереди. Изменения других групп тоже передаются: по ним
+// подписчик продвигает LastEventID. Вызывается под stream.mu.
+func (sub *ChangeSubscription) offer(change domain.SongChange) bool {
+	select {
+	case sub.live <- change:
+		return true
+	default:
+		return false
+	}
+}
+
+// matches сообщает, относится ли изменение к группам подписки. Группы
+// сравниваются без учета регистра.
+func (sub *ChangeSubscription) matches(change domain.SongChange) bool {
+	if len(sub.groups) == 0 {
+		return true
+	}
+	for _, group := range sub.groups {
+		if strings.EqualFold(change.Group, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// LastEventID возвращает токен синхронизации после последнего просмотренного
+// изменения, в том числе не относящегося к группам подписки. С ним можно
+// продолжить поток или запросить /changes.
+func (sub *ChangeSubscription) LastEventID() string {
+	return syncToken(sub.logID, sub.after)
+}
+
+// Close отменяет подписку.
+func (sub *ChangeSubscription) Close() {
+	sub.stream.mu.Lock()
+	defer sub.stream.mu.Unlock()
+	sub.stream.unsubscribe(sub, ErrStreamClosed)
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"song-library/domain"
+	"song-library/i18n"
+	"song-library/repository"
+	"song-library/tracing"
+	"song-library/validation"
+)
+
+// maxBatchOperations ограничивает количество операций в одном пакете.
+const maxBatchOperations = 1000
+
+// errBatchAborted прерывает транзакцию пакета в режиме all_or_nothing.
+var errBatchAborted = errors.New("пакет прерван из-за ошибки операции")
+
+// ExecuteBatch выполняет пакет операций над песнями в одной транзакции.
+// В режиме all_or_nothing первая ошибка откатывает весь пакет, в режиме
+// best_effort каждая операция выполняется в своей точке сохранения и
+// ошибочные операции откатываются по отдельности.
+func (service *SongService) ExecuteBatch(ctx context.Context, request domain.BatchRequest) (_ *domain.BatchResponse, err error) {
+	ctx, span := tracer.Start(ctx, "SongService.ExecuteBatch")
+	defer func() { tracing.End(span, err) }()
+
+	mode := request.Mode
+	if mode == "" {
+		mode = domain.BatchModeAllOrNothing
+	}
+	if mode != domain.BatchModeAllOrNothing && mode != domain.BatchModeBestEffort {
+		err := domain.NewValidationError("mode", "unknown_value", request.Mode)
+		service.log.WarnContext(ctx, "ошибка в ExecuteBatch", "error", err)
+		return nil, err
+	}
+	if len(request.Operations) == 0 || len(request.Operations) > maxBatchOperations {
+		err := domain.NewValidationError("operations", "batch_size", maxBatchOperations, len(request.Operations))
+		service.log.WarnContext(ctx, "ошибка в ExecuteBatch", "error", err)
+		return nil, err
+	}
+
+	lang := i18n.FromContext(ctx)
+	operations := make([]domain.BatchOperation, len(request.Operations))
+	copy(operations, request.Operations)
+	results := make([]domain.BatchResult, len(operations))
+	prepareFailed := false
+	for i := range operations {
+		results[i] = domain.BatchResult{Index: i, Op: operations[i].Op, ID: operations[i].ID}
+		if err := service.prepareBatchOperation(ctx, &operations[i]); err != nil {
+			results[i].Status = domain.BatchStatusFailed
+			results[i].Error = domain.LocalizeError(lang, err)
+			prepareFailed = true
+		}
+	}
+
+	response := &domain.BatchResponse{Mode: mode, Results: results}
+	if prepareFailed && mode == domain.BatchModeAllOrNothing {
+		markBatch(results, domain.BatchStatusSkipped)
+		service.log.InfoContext(ctx, "пакет отклонен до начала транзакции", "operations", len(operations))
+		return summarizeBatch(response), nil
+	}
+
+	err = service.repo.InTx(ctx, func(tx repository.SongStore) error {
+		for i, operation := range operations {
+			if results[i].Status == domain.BatchStatusFailed {
+				continue
+			}
+
+			run := func() error { return applyBatchOperation(ctx, tx, operation, &results[i]) }
+			var err error
+			if mode == domain.BatchModeBestEffort {
+				err = tx.Savepoint(ctx, fmt.Sprintf("batch_operation_%d", i), run)
+			} else {
+				err = run()
+			}
+
+			if err != nil {
+				results[i].Status = domain.BatchStatusFailed
+				results[i].Error = domain.LocalizeError(lang, err)
+				if mode == domain.BatchModeAllOrNothing {
+					return errBatchAborted
+				}
+				continue
+			}
+			results[i].Status = domain.BatchStatusOK
+		}
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		response.Committed = true
+	case errors.Is(err, errBatchAborted):
+		markBatch(results, domain.BatchStatusSkipped)
+		markExecuted(results, domain.BatchStatusRolledBack)
+	default:
+		service.log.ErrorContext(ctx, "ошибка транзакции пакета", "operations", len(operations), "error", err)
+		return nil, fmt.Errorf("ошибка выполнения пакета: %w", err)
+	}
+
+	summarizeBatch(response)
+	service.log.InfoContext(ctx, "пакет выполнен",
+		"mode", mode, "committed", response.Committed, "succeeded", response.Succeeded, "failed", response.Failed)
+	return response, nil
+}
+
+// prepareBatchOperation проверяет операцию и до открытия транзакции
+// запрашивает во внешнем API детали для добавляемых песен.
+func (service *SongService) prepareBatchOperation(ctx context.Context, operation *domain.BatchOperation) error {
+	// Без данных песни create и update не пройдут проверку обязательных полей
+	song := operation.Song
+	if song == nil {
+		song = &domain.Song{}
+	}
+
+	switch operation.Op {
+	case domain.BatchOpCreate:
+		request := domain.SongCreateRequest{Group: song.Group, Song: song.Song}
+		if err := validation.Validate(&request); err != nil {
+			return err
+		}
+		details, err := service.lookupSongDetails(ctx, request.Group, request.Song)
+		if err != nil {
+			return fmt.Errorf("ошибка получения данных из внешнего API: %w", err)
+		}
+		operation.Song = &domain.Song{
+			Group:       request.Group,
+			Song:        request.Song,
+			ReleaseDate: details.ReleaseDate,
+			Text:        details.Text,
+			Link:        details.Link,
+		}
+		return nil
+	case domain.BatchOpUpdate:
+		if operation.ID <= 0 {
+			return invalidSongID(operation.ID)
+		}
+		request := domain.SongUpdateRequest{
+			Group:       song.Group,
+			Song:        song.Song,
+			ReleaseDate: song.ReleaseDate,
+			Text:        song.Text,
+			Link:        song.Link,
+		}
+		if err := validation.Validate(&request); err != nil {
+			return err
+		}
+		updated := request.ToSong(operation.ID)
+		operation.Song = &updated
+		return nil
+	case domain.BatchOpDelete:
+		if operation.ID <= 0 {
+			return invalidSongID(operation.ID)
+		}
+		return nil
+	default:
+		return domain.NewValidationError("op", "unknown_value", operation.Op)
+	}
+}
+
+// applyBatchOperation выполняет подготовленную операцию в транзакции пакета.
+func applyBatchOperation(ctx context.Context, tx repository.SongStore, operation domain.BatchOperation, result *domain.BatchResult) error {
+	switch operation.Op {
+	case domain.BatchOpCreate:
+		id, err := tx.AddSong(ctx, *operation.Song)
+		if err != nil {
+			return err
+		}
+		result.ID = id
+		return nil
+	case domain.BatchOpUpdate:
+		return tx.UpdateSong(ctx, *operation.Song)
+	default:
+		return tx.DeleteSong(ctx, operation.ID)
+	}
+}
+
+// markBatch присваивает статус status операциям, которые еще не выполнялись.
+func markBatch(results []domain.BatchResult, status string) {
+	for i := range results {
+		if results[i].Status == "" {
+			results[i].Status = status
+		}
+	}
+}
+
+// markExecuted присваивает статус status успешно выполненным операциям.
+func markExecuted(results []domain.BatchResult, status string) {
+	for i := range results {
+		if results[i].Status == domain.BatchStatusOK {
+			results[i].Status = status
+		}
+	}
+}
+
+// summarizeBatch подсчитывает итоговые счетчики пакета.
+func summarizeBatch(response *domain.BatchResponse) *domain.BatchResponse {
+	response.Succeeded, response.Failed = 0, 0
+	for _, result := range response.Results {
+		switch result.Status {
+		case domain.BatchStatusOK:
+			response.Succeeded++
+		case domain.BatchStatusFailed:
+			response.Failed++
+		}
+	}
+	return response
+}
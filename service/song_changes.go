@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"song-library/domain"
+	"song-library/tracing"
+)
+
+// maxChangesPage ограничивает количество изменений в одном ответе GetChanges.
+const maxChangesPage = 1000
+
+// ErrSyncTokenExpired возвращается для токена, изменения после которого уже
+// удалены из журнала или который выдан другим журналом (например, до
+// восстановления базы из архива). Клиенту нужно загрузить библиотеку заново.
+var ErrSyncTokenExpired = domain.NewNotFoundError(domain.CodeSyncTokenExpired, "токен синхронизации устарел")
+
+// GetChanges возвращает до limit изменений песен, сделанных после токена
+// since, в порядке фиксации, и токен для следующего запроса. Пустой since
+// означает "с текущего момента": изменений нет, возвращается только токен.
+func (service *SongService) GetChanges(ctx context.Context, since string, limit int) (_ *domain.ChangeFeed, err error) {
+	ctx, span := tracer.Start(ctx, "SongService.GetChanges")
+	defer func() { tracing.End(span, err) }()
+
+	if limit <= 0 {
+		err := domain.NewValidationError("limit", "positive", limit)
+		service.log.WarnContext(ctx, "ошибка в GetChanges", "error", err)
+		return nil, err
+	}
+	limit = min(limit, maxChangesPage)
+
+	var logID string
+	var after int64
+	if since != "" {
+		var ok bool
+		if logID, after, ok = parseSyncToken(since); !ok {
+			err := domain.NewValidationError("since", "sync_token")
+			service.log.WarnContext(ctx, "ошибка в GetChanges", "since", since, "error", err)
+			return nil, err
+		}
+	}
+
+	// Лишняя запись показывает, что после страницы есть еще изменения
+	state, changes, err := service.repo.GetChanges(ctx, after, limit+1)
+	if err != nil {
+		service.log.ErrorContext(ctx, "ошибка чтения журнала изменений", "after", after, "error", err)
+		return nil, fmt.Errorf("ошибка чтения журнала изменений: %w", err)
+	}
+
+	if since == "" {
+		return &domain.ChangeFeed{Changes: []domain.SongChange{}, Next: syncToken(state.LogID, state.Latest)}, nil
+	}
+	if logID != state.LogID || after < state.PrunedThrough || after > state.Latest {
+		service.log.InfoContext(ctx, "токен синхронизации устарел", "since", since, "pruned_through", state.PrunedThrough, "latest", state.Latest)
+		return nil, ErrSyncTokenExpired
+	}
+
+	feed := &domain.ChangeFeed{Changes: changes, Next: since}
+	if len(changes) > limit {
+		feed.Changes, feed.HasMore = changes[:limit], true
+	}
+	if len(feed.Changes) > 0 {
+		feed.Next = syncToken(state.LogID, feed.Changes[len(feed.Changes)-1].Seq)
+	} else {
+		feed.Changes = []domain.SongChange{}
+	}
+
+	service.log.InfoContext(ctx, "успешно выполнен GetChanges", "count", len(feed.Changes), "has_more", feed.HasMore)
+	return feed, nil
+}
+
+// PruneChanges удаляет из журнала изменения старше retention. Токены,
+// указывающие на удаленные изменения, после этого устаревают.
+func (service *SongService) PruneChanges(ctx context.Context, retention time.Duration) (int64, error) {
+	pruned, err := service.repo.PruneChanges(ctx, time.Now().Add(-retention))
+	if err != nil {
+		service.log.ErrorContext(ctx, "ошибка очистки журнала изменений", "error", err)
+		return 0, fmt.Errorf("ошибка очистки журнала изменений: %w", err)
+	}
+	if pruned > 0 {
+		service.log.InfoContext(ctx, "журнал изменений очищен", "pruned", pruned, "retention", retention)
+	}
+	return pruned, nil
+}
+
+// syncToken составляет токен синхронизации из идентификатора журнала и номера
+// последнего полученного изменения.
+func syncToken(logID string, seq int64) string {
+	return logID + "." + strconv.FormatInt(seq, 10)
+}
+
+// parseSyncToken разбирает токен, составленный syncToken.
+func parseSyncToken(token string) (logID string, seq int64, ok bool) {
+	logID, rawSeq, found := strings.Cut(token, ".")
+	if !found || logID == "" {
+		return "", 0, false
+	}
+	seq, err := strconv.ParseInt(rawSeq, 10, 64)
+	if err != nil || seq < 0 {
+		return "", 0, false
+	}
+	return logID, seq, true
+}
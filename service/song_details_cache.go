@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"song-library/cache"
+	"song-library/domain"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrSongDetailsNotFound возвращается, когда внешний API не знает запрошенную песню.
+var ErrSongDetailsNotFound = domain.NewNotFoundError(domain.CodeUpstreamSongNotFound, "песня не найдена во внешнем API")
+
+// SongDetailsStore описывает постоянное хранилище кэша деталей песен.
+type SongDetailsStore interface {
+	GetDetails(ctx context.Context, key string) (*domain.SongDetailCacheEntry, error)
+	PutDetails(ctx context.Context, key string, entry domain.SongDetailCacheEntry) error
+}
+
+// SongDetailsCacheConfig задает параметры кэша деталей песен.
+type SongDetailsCacheConfig struct {
+	Size        int           // Вместимость кэша в памяти
+	TTL         time.Duration // Время жизни успешного ответа
+	NegativeTTL time.Duration // Время жизни ответа "песня не найдена"
+}
+
+// CacheStats содержит счетчики кэша деталей песен.
+type CacheStats struct {
+	Hits           uint64 `json:"hits"`            // Ответы из памяти или постоянного хранилища
+	Misses         uint64 `json:"misses"`          // Запросы, ушедшие во внешний API
+	NegativeHits   uint64 `json:"negative_hits"`   // Попадания в отрицательные записи
+	PersistentHits uint64 `json:"persistent_hits"` // Попадания в постоянное хранилище
+	Collapsed      uint64 `json:"collapsed"`       // Запросы, объединенные с уже выполняющимся
+	Errors         uint64 `json:"errors"`          // Ошибки внешнего API, не попавшие в кэш
+	Size           int    `json:"size"`            // Количество записей в памяти
+}
+
+// SongDetailsCache кэширует ответы внешнего API в памяти и, опционально, в базе данных.
+// Одновременные запросы одной и той же песни объединяются в один запрос к API.
+type SongDetailsCache struct {
+	memory *cache.LRU[string, domain.SongDetailCacheEntry]
+	store  SongDetailsStore
+	config SongDetailsCacheConfig
+	group  singleflight.Group
+	log    *slog.Logger
+
+	hits           atomic.Uint64
+	misses         atomic.Uint64
+	negativeHits   atomic.Uint64
+	persistentHits atomic.Uint64
+	collapsed      atomic.Uint64
+	errors         atomic.Uint64
+}
+
+// NewSongDetailsCache создает кэш деталей песен. store может быть nil,
+// тогда записи хранятся только в памяти.
+func NewSongDetailsCache(config SongDetailsCacheConfig, store SongDetailsStore, logger *slog.Logger) *SongDetailsCache {
+	return &SongDetailsCache{
+		memory: cache.NewLRU[string, domain.SongDetailCacheEntry](config.Size),
+		store:  store,
+		config: config,
+		log:    logger,
+	}
+}
+
+// Get возвращает детали песни из кэша, а при промахе вызывает fetch и сохраняет результат.
+// Одновременные промахи по одному ключу выполняют fetch один раз с контекстом
+// первого запроса; остальные ждут результат, пока не отменен их собственный ctx.
+func (c *SongDetailsCache) Get(ctx context.Context, group, song string, fetch func(ctx context.Context) (*domain.SongDetail, error)) (*domain.SongDetail, error) {
+	key := songDetailsCacheKey(group, song)
+
+	if entry, ok := c.memory.Get(key); ok {
+		return c.hit(entry)
+	}
+
+	for {
+		result := c.flight(ctx, key, fetch)
+		if result.Shared {
+			c.collapsed.Add(1)
+		}
+
+		// Запрос, к которому присоединился этот вызов, мог быть отменен своим
+		// клиентом; в этом случае пробуем снова, если наш контекст еще жив.
+		if result.Err != nil && result.Shared && isContextError(result.Err) && ctx.Err() == nil {
+			continue
+		}
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		entry := result.Val.(domain.SongDetailCacheEntry)
+		if entry.NotFound {
+			return nil, ErrSongDetailsNotFound
+		}
+		return copySongDetail(entry.Detail), nil
+	}
+}
+
+// flight запускает или присоединяется к общему запросу по ключу и прекращает
+// ожидание при отмене ctx.
+func (c *SongDetailsCache) flight(ctx context.Context, key string, fetch func(ctx context.Context) (*domain.SongDetail, error)) singleflight.Result {
+	results := c.group.DoChan(key, func() (interface{}, error) {
+		if entry, ok := c.loadPersistent(ctx, key); ok {
+			c.persistentHits.Add(1)
+			return entry, nil
+		}
+
+		c.misses.Add(1)
+		details, err := fetch(ctx)
+		switch {
+		case err == nil:
+			entry := domain.SongDetailCacheEntry{Detail: details, ExpiresAt: time.Now().Add(c.config.TTL)}
+			c.save(ctx, key, entry, c.config.TTL)
+			return entry, nil
+		case errors.Is(err, ErrSongDetailsNotFound):
+			entry := domain.SongDetailCacheEntry{NotFound: true, ExpiresAt: time.Now().Add(c.config.NegativeTTL)}
+			c.save(ctx, key, entry, c.config.NegativeTTL)
+			return entry, nil
+		default:
+			c.errors.Add(1)
+			return nil, err
+		}
+	})
+
+	select {
+	case result := <-results:
+		return result
+	case <-ctx.Done():
+		return singleflight.Result{Err: ctx.Err()}
+	}
+}
+
+// Stats возвращает текущие значения счетчиков кэша.
+func (c *SongDetailsCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:           c.hits.Load() + c.persistentHits.Load(),
+		Misses:         c.misses.Load(),
+		NegativeHits:   c.negativeHits.Load(),
+		PersistentHits: c.persistentHits.Load(),
+		Collapsed:      c.collapsed.Load(),
+		Errors:         c.errors.Load(),
+		Size:           c.memory.Len(),
+	}
+}
+
+func (c *SongDetailsCache) hit(entry domain.SongDetailCacheEntry) (*domain.SongDetail, error) {
+	c.hits.Add(1)
+	if entry.NotFound {
+		c.negativeHits.Add(1)
+		return nil, ErrSongDetailsNotFound
+	}
+	return copySongDetail(entry.Detail), nil
+}
+
+// loadPersistent ищет запись в постоянном хранилище и переносит ее в память.
+func (c *SongDetailsCache) loadPersistent(ctx context.Context, key string) (domain.SongDetailCacheEntry, bool) {
+	if c.store == nil {
+		return domain.SongDetailCacheEntry{}, false
+	}
+
+	entry, err := c.store.GetDetails(ctx, key)
+	if err != nil {
+		c.log.ErrorContext(ctx, "ошибка чтения постоянного кэша, запрос уйдет во внешний API", "key", key, "error", err)
+		return domain.SongDetailCacheEntry{}, false
+	}
+	if entry == nil {
+		return domain.SongDetailCacheEntry{}, false
+	}
+
+	if entry.NotFound {
+		c.negativeHits.Add(1)
+	}
+	c.memory.Set(key, *entry, time.Until(entry.ExpiresAt))
+	return *entry, true
+}
+
+func (c *SongDetailsCache) save(ctx context.Context, key string, entry domain.SongDetailCacheEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.memory.Set(key, entry, ttl)
+	if c.store != nil {
+		if err := c.store.PutDetails(ctx, key, entry); err != nil {
+			c.log.ErrorContext(ctx, "ошибка записи постоянного кэша", "key", key, "error", err)
+		}
+	}
+}
+
+// isContextError сообщает, что ошибка вызвана отменой или истечением контекста.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// songDetailsCacheKey нормализует группу и название песни в ключ кэша.
+func songDetailsCacheKey(group, song string) string {
+	return strings.ToLower(strings.TrimSpace(group)) + "\x1f" + strings.ToLower(strings.TrimSpace(song))
+}
+
+func copySongDetail(detail *domain.SongDetail) *domain.SongDetail {
+	if detail == nil {
+		return nil
+	}
+	copied := *detail
+	return &copied
+}
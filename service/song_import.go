@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"song-library/domain"
+	"song-library/i18n"
+	"song-library/songio"
+	"song-library/tracing"
+	"song-library/validation"
+)
+
+const (
+	defaultImportBatchSize = 500
+	// maxImportBatchSize не дает превысить лимит параметров PostgreSQL (65535) в одном INSERT.
+	maxImportBatchSize = 5000
+)
+
+// ImportSongs читает песни из reader, проверяет каждую строку и сохраняет их пакетами.
+// Ошибки отдельных строк попадают в отчет; ошибка возвращается, только если
+// дальнейшее чтение потока невозможно.
+func (service *SongService) ImportSongs(ctx context.Context, reader songio.Reader, options domain.ImportOptions) (_ *domain.ImportReport, err error) {
+	ctx, span := tracer.Start(ctx, "SongService.ImportSongs")
+	defer func() { tracing.End(span, err) }()
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultImportBatchSize
+	}
+	if batchSize > maxImportBatchSize {
+		batchSize = maxImportBatchSize
+	}
+
+	report := &domain.ImportReport{DryRun: options.DryRun, Rows: []domain.ImportRowResult{}}
+	seen := make(map[string]struct{})
+	batch := make([]songio.Record, 0, batchSize)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			service.log.InfoContext(ctx, "импорт прерван", "error", err)
+			abandonImportBatch(ctx, batch, report)
+			report.SortRows()
+			return report, fmt.Errorf("импорт прерван: %w", err)
+		}
+
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			service.log.ErrorContext(ctx, "ошибка чтения файла импорта", "line", record.Line, "error", err)
+			// Сохраненные пакеты остаются в базе; строки текущего пакета не сохраняются
+			abandonImportBatch(ctx, batch, report)
+			report.SortRows()
+			return report, domain.NewMalformedBodyError(fmt.Errorf("ошибка чтения файла импорта: %w", err))
+		}
+
+		row := domain.ImportRowResult{Line: record.Line, Group: record.Song.Group, Song: record.Song.Song}
+		if record.Err != nil {
+			row.Status = domain.ImportStatusFailed
+			row.Error = record.Err.Error()
+			report.Add(row)
+			continue
+		}
+
+		if err := validation.Validate(&record.Song); err != nil {
+			row.Status = domain.ImportStatusFailed
+			row.Error = domain.LocalizeError(i18n.FromContext(ctx), err)
+			report.Add(row)
+			continue
+		}
+
+		key := record.Song.Group + "\x1f" + record.Song.Song
+		if _, ok := seen[key]; ok {
+			row.Status = domain.ImportStatusSkippedDuplicate
+			report.Add(row)
+			continue
+		}
+		seen[key] = struct{}{}
+
+		if options.Enrich && record.Song.NeedsEnrichment() {
+			if err := service.enrichImportedSong(ctx, &record.Song); err != nil {
+				row.Status = domain.ImportStatusFailed
+				row.Error = domain.LocalizeError(i18n.FromContext(ctx), err)
+				report.Add(row)
+				continue
+			}
+		}
+
+		batch = append(batch, record)
+		if len(batch) == batchSize {
+			service.flushImportBatch(ctx, batch, options.DryRun, report)
+			batch = batch[:0]
+		}
+	}
+	service.flushImportBatch(ctx, batch, options.DryRun, report)
+	report.SortRows()
+
+	service.log.InfoContext(ctx, "импорт завершен",
+		"created", report.Created, "skipped", report.SkippedDuplicate, "failed", report.Failed, "dry_run", report.DryRun)
+	return report, nil
+}
+
+// flushImportBatch сохраняет пакет строк и добавляет их результаты в отчет.
+func (service *SongService) flushImportBatch(ctx context.Context, batch []songio.Record, dryRun bool, report *domain.ImportReport) {
+	if len(batch) == 0 {
+		return
+	}
+
+	songs := make([]domain.Song, len(batch))
+	for i, record := range batch {
+		songs[i] = record.Song
+	}
+
+	created, err := service.repo.InsertSongs(ctx, songs, dryRun)
+	for i, record := range batch {
+		row := domain.ImportRowResult{Line: record.Line, Group: record.Song.Group, Song: record.Song.Song}
+		switch {
+		case err != nil:
+			row.Status = domain.ImportStatusFailed
+			row.Error = domain.LocalizeError(i18n.FromContext(ctx), err)
+		case created[i]:
+			row.Status = domain.ImportStatusCreated
+		default:
+			row.Status = domain.ImportStatusSkippedDuplicate
+		}
+		report.Add(row)
+	}
+
+	if err != nil {
+		service.log.ErrorContext(ctx, "ошибка сохранения пакета импорта", "first_line", batch[0].Line, "last_line", batch[len(batch)-1].Line, "error", err)
+	}
+}
+
+// abandonImportBatch добавляет в отчет строки несохраненного пакета как
+// неудачные, чтобы прерванный импорт не терял их из отчета.
+func abandonImportBatch(ctx context.Context, batch []songio.Record, report *domain.ImportReport) {
+	message := i18n.T(i18n.FromContext(ctx), "import.not_saved")
+	for _, record := range batch {
+		report.Add(domain.ImportRowResult{
+			Line:   record.Line,
+			Group:  record.Song.Group,
+			Song:   record.Song.Song,
+			Status: domain.ImportStatusFailed,
+			Error:  message,
+		})
+	}
+}
+
+// enrichImportedSong дополняет песню недостающими деталями из внешнего API.
+func (service *SongService) enrichImportedSong(ctx context.Context, song *domain.Song) error {
+	details, err := service.lookupSongDetails(ctx, song.Group, song.Song)
+	if err != nil {
+		return fmt.Errorf("ошибка получения данных из внешнего API: %w", err)
+	}
+
+	fillSongDetails(song, details)
+	return nil
+}
+
+// fillSongDetails заполняет пустые детали песни значениями из details.
+func fillSongDetails(song *domain.Song, details *domain.SongDetail) {
+	if song.ReleaseDate == "" {
+		song.ReleaseDate = details.ReleaseDate
+	}
+	if song.Text == "" {
+		song.Text = details.Text
+	}
+	if song.Link == "" {
+		song.Link = details.Link
+	}
+}
@@ -0,0 +1,58 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+
+	"song-library/domain"
+	"song-library/repository"
+	"song-library/service"
+	"song-library/songio"
+)
+
+// failingReader отдает records, а затем возвращает ошибку чтения.
+type failingReader struct {
+	records []songio.Record
+}
+
+func (reader *failingReader) Next() (songio.Record, error) {
+	if len(reader.records) == 0 {
+		return songio.Record{Line: 99}, errors.New("битый поток")
+	}
+	record := reader.records[0]
+	reader.records = reader.records[1:]
+	return record, nil
+}
+
+func TestImportSongsReportsPendingBatchOnReadError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repository.NewMemorySongStore(logger)
+	songService := service.NewSongService(store, logger, "", nil, nil)
+
+	reader := &failingReader{}
+	for line := 2; line <= 4; line++ {
+		reader.records = append(reader.records, songio.Record{Line: line, Song: domain.Song{
+			Group: "Muse", Song: "Song " + strconv.Itoa(line), ReleaseDate: "16.07.2006",
+			Text: "text", Link: "https://example.com",
+		}})
+	}
+
+	// Пакет из двух строк сохраняется, третья строка остается в незаписанном пакете
+	report, err := songService.ImportSongs(context.Background(), reader, domain.ImportOptions{BatchSize: 2})
+	if err == nil {
+		t.Fatal("ImportSongs не вернул ошибку чтения")
+	}
+	if report == nil || len(report.Rows) != 3 {
+		t.Fatalf("отчет = %+v, ожидались три строки", report)
+	}
+	if report.Created != 2 || report.Failed != 1 {
+		t.Errorf("создано %d, ошибок %d; ожидалось 2 и 1", report.Created, report.Failed)
+	}
+	if last := report.Rows[2]; last.Line != 4 || last.Status != domain.ImportStatusFailed || last.Error == "" {
+		t.Errorf("строка незаписанного пакета = %+v, ожидалась failed с причиной", last)
+	}
+}
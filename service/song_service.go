@@ -1,55 +1,108 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
 	"song-library/domain"
+	"song-library/logging"
 	"song-library/repository"
+	"song-library/songio"
+	"song-library/tracing"
+	"song-library/validation"
 )
 
+var tracer = otel.Tracer("song-library/service")
+
 type SongService struct {
-	repo       *repository.SongRepository
-	log        *log.Logger
-	apiBaseURL string
+	repo         repository.SongStore
+	log          *slog.Logger
+	apiBaseURL   string
+	client       *http.Client
+	detailsCache *SongDetailsCache
 }
 
-func NewSongService(repo *repository.SongRepository, logger *log.Logger, apiBaseURL string) *SongService {
-	return &SongService{repo: repo, log: logger, apiBaseURL: apiBaseURL}
+// NewSongService создает новый SongService. client выполняет запросы к внешнему
+// API; nil означает http.DefaultClient. detailsCache может быть nil, тогда
+// каждый запрос деталей песни уходит во внешний API.
+func NewSongService(repo repository.SongStore, logger *slog.Logger, apiBaseURL string, client *http.Client, detailsCache *SongDetailsCache) *SongService {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &SongService{repo: repo, log: logger, apiBaseURL: apiBaseURL, client: client, detailsCache: detailsCache}
 }
 
-// GetLibrary получает список песен с учетом пагинации.
-func (service *SongService) GetLibrary(page, limit int) ([]domain.Song, error) {
+// GetLibrary получает список песен, удовлетворяющих фильтру, с учетом пагинации.
+func (service *SongService) GetLibrary(ctx context.Context, filter domain.SongFilter, page, limit int) (_ []domain.Song, err error) {
+	ctx, span := tracer.Start(ctx, "SongService.GetLibrary")
+	defer func() { tracing.End(span, err) }()
+
 	if page <= 0 || limit <= 0 {
-		err := fmt.Errorf("некорректные параметры пагинации: page=%d, limit=%d", page, limit)
-		service.log.Printf("ошибка в GetLibrary: %v", err)
+		err := &domain.ValidationError{}
+		if page <= 0 {
+			err.Add("page", "positive", page)
+		}
+		if limit <= 0 {
+			err.Add("limit", "positive", limit)
+		}
+		service.log.WarnContext(ctx, "ошибка в GetLibrary", "error", err)
 		return nil, err
 	}
 
 	offset := calculateOffset(page, limit)
-	songs, err := service.repo.GetSongs(offset, limit)
+	songs, err := service.repo.GetSongs(ctx, filter, offset, limit)
 	if err != nil {
-		service.log.Printf("ошибка получения песен в GetLibrary: offset=%d, limit=%d, error=%v", offset, limit, err)
+		service.log.ErrorContext(ctx, "ошибка получения песен в GetLibrary", "offset", offset, "limit", limit, "error", err)
 		return nil, fmt.Errorf("ошибка получения библиотеки песен: %w", err)
 	}
 
-	service.log.Printf("успешно выполнен GetLibrary: page=%d, limit=%d", page, limit)
+	service.log.InfoContext(ctx, "успешно выполнен GetLibrary", "page", page, "limit", limit)
 	return songs, nil
 }
 
+// ExportSongs передает в writer все песни, удовлетворяющие фильтру, в порядке ID.
+func (service *SongService) ExportSongs(ctx context.Context, filter domain.SongFilter, writer songio.Writer) (err error) {
+	ctx, span := tracer.Start(ctx, "SongService.ExportSongs")
+	defer func() { tracing.End(span, err) }()
+
+	if err := service.repo.StreamSongs(ctx, filter, writer.Write); err != nil {
+		service.log.ErrorContext(ctx, "ошибка экспорта песен", "error", err)
+		return fmt.Errorf("ошибка экспорта песен: %w", err)
+	}
+
+	if err := writer.Flush(); err != nil {
+		service.log.ErrorContext(ctx, "ошибка записи экспорта", "error", err)
+		return fmt.Errorf("ошибка записи экспорта: %w", err)
+	}
+
+	service.log.InfoContext(ctx, "успешно выполнен ExportSongs")
+	return nil
+}
+
 // AddSong добавляет новую песню с запросом к внешнему API для получения деталей.
-func (service *SongService) AddSong(song domain.Song) error {
-	if song.Group == "" || song.Song == "" {
-		err := fmt.Errorf("группа и название песни не могут быть пустыми")
-		service.log.Printf("ошибка в AddSong: %v", err)
+func (service *SongService) AddSong(ctx context.Context, request domain.SongCreateRequest) (err error) {
+	ctx, span := tracer.Start(ctx, "SongService.AddSong")
+	defer func() { tracing.End(span, err) }()
+
+	if err := validation.Validate(&request); err != nil {
+		service.log.WarnContext(ctx, "ошибка в AddSong", "error", err)
 		return err
 	}
+	song := domain.Song{Group: request.Group, Song: request.Song}
 
 	// Получение данных из внешнего API
-	details, err := service.fetchSongDetails(song.Group, song.Song)
+	details, err := service.lookupSongDetails(ctx, song.Group, song.Song)
 	if err != nil {
-		service.log.Printf("ошибка получения данных из внешнего API: group=%s, song=%s, error=%v", song.Group, song.Song, err)
+		service.log.ErrorContext(ctx, "ошибка получения данных из внешнего API", "group", song.Group, "song", song.Song, "error", err)
 		return fmt.Errorf("ошибка получения данных из внешнего API: %w", err)
 	}
 
@@ -59,105 +112,255 @@ func (service *SongService) AddSong(song domain.Song) error {
 	song.Link = details.Link
 
 	// Добавляем песню в базу данных
-	if err := service.repo.AddSong(song); err != nil {
-		service.log.Printf("ошибка добавления песни в базу данных: group=%s, song=%s, error=%v", song.Group, song.Song, err)
+	id, err := service.repo.AddSong(ctx, song)
+	if err != nil {
+		service.log.ErrorContext(ctx, "ошибка добавления песни в базу данных", "group", song.Group, "song", song.Song, "error", err)
 		return fmt.Errorf("ошибка добавления песни в базу данных: %w", err)
 	}
+	logging.SetSongID(ctx, id)
 
-	service.log.Printf("песня успешно добавлена: group=%s, song=%s", song.Group, song.Song)
+	service.log.InfoContext(ctx, "песня успешно добавлена", "song_id", id, "group", song.Group, "song", song.Song)
 	return nil
 }
 
-// UpdateSong обновляет существующую песню.
-func (service *SongService) UpdateSong(song domain.Song) error {
-	if song.ID <= 0 {
-		err := fmt.Errorf("некорректный ID песни: %d", song.ID)
-		service.log.Printf("ошибка в UpdateSong: %v", err)
+// UpdateSong заменяет данные существующей песни с идентификатором id.
+func (service *SongService) UpdateSong(ctx context.Context, id int, request domain.SongUpdateRequest) (err error) {
+	ctx, span := tracer.Start(ctx, "SongService.UpdateSong")
+	defer func() { tracing.End(span, err) }()
+
+	if id <= 0 {
+		err := invalidSongID(id)
+		service.log.WarnContext(ctx, "ошибка в UpdateSong", "error", err)
+		return err
+	}
+	if err := validation.Validate(&request); err != nil {
+		service.log.WarnContext(ctx, "ошибка в UpdateSong", "song_id", id, "error", err)
 		return err
 	}
+	song := request.ToSong(id)
 
-	if err := service.repo.UpdateSong(song); err != nil {
-		service.log.Printf("ошибка обновления песни: id=%d, error=%v", song.ID, err)
+	if err := service.repo.UpdateSong(ctx, song); err != nil {
+		service.log.ErrorContext(ctx, "ошибка обновления песни", "song_id", song.ID, "error", err)
 		return fmt.Errorf("ошибка обновления песни: %w", err)
 	}
 
-	service.log.Printf("песня успешно обновлена: id=%d", song.ID)
+	service.log.InfoContext(ctx, "песня успешно обновлена", "song_id", song.ID)
 	return nil
 }
 
 // DeleteSong удаляет песню по ID.
-func (service *SongService) DeleteSong(id int) error {
+func (service *SongService) DeleteSong(ctx context.Context, id int) (err error) {
+	ctx, span := tracer.Start(ctx, "SongService.DeleteSong")
+	defer func() { tracing.End(span, err) }()
+
 	if id <= 0 {
-		err := fmt.Errorf("некорректный ID песни: %d", id)
-		service.log.Printf("ошибка в DeleteSong: %v", err)
+		err := invalidSongID(id)
+		service.log.WarnContext(ctx, "ошибка в DeleteSong", "error", err)
 		return err
 	}
 
-	if err := service.repo.DeleteSong(id); err != nil {
-		service.log.Printf("ошибка удаления песни: id=%d, error=%v", id, err)
+	if err := service.repo.DeleteSong(ctx, id); err != nil {
+		service.log.ErrorContext(ctx, "ошибка удаления песни", "song_id", id, "error", err)
 		return fmt.Errorf("ошибка удаления песни: %w", err)
 	}
 
-	service.log.Printf("песня успешно удалена: id=%d", id)
+	service.log.InfoContext(ctx, "песня успешно удалена", "song_id", id)
 	return nil
 }
 
+// EnrichSong дополняет песню id недостающими деталями из внешнего API и
+// возвращает ее. Уже заполненные детали не меняются; если заполнены все,
+// внешний API не запрашивается. Подписчики получают событие song.enriched.
+func (service *SongService) EnrichSong(ctx context.Context, id int) (_ *domain.Song, err error) {
+	ctx, span := tracer.Start(ctx, "SongService.EnrichSong")
+	defer func() { tracing.End(span, err) }()
+
+	if id <= 0 {
+		err := invalidSongID(id)
+		service.log.WarnContext(ctx, "ошибка в EnrichSong", "error", err)
+		return nil, err
+	}
+
+	song, err := service.repo.GetSongByID(ctx, id)
+	if err != nil {
+		service.log.ErrorContext(ctx, "ошибка получения песни", "song_id", id, "error", err)
+		return nil, fmt.Errorf("ошибка получения песни: %w", err)
+	}
+	if !song.NeedsEnrichment() {
+		service.log.InfoContext(ctx, "у песни уже есть все детали", "song_id", id)
+		return song, nil
+	}
+
+	// Внешний API запрашивается вне транзакции; песня перечитывается в ней,
+	// чтобы не затереть изменения, сделанные за время запроса
+	details, err := service.lookupSongDetails(ctx, song.Group, song.Song)
+	if err != nil {
+		service.log.ErrorContext(ctx, "ошибка получения данных из внешнего API", "song_id", id, "error", err)
+		return nil, fmt.Errorf("ошибка получения данных из внешнего API: %w", err)
+	}
+
+	err = service.repo.InTx(ctx, func(tx repository.SongStore) error {
+		current, err := tx.GetSongByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		enriched := *current
+		fillSongDetails(&enriched, details)
+		if enriched == *current {
+			song = current
+			return nil
+		}
+		if err := tx.EnrichSong(ctx, enriched); err != nil {
+			return err
+		}
+		song, err = tx.GetSongByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		service.log.ErrorContext(ctx, "ошибка сохранения деталей песни", "song_id", id, "error", err)
+		return nil, fmt.Errorf("ошибка сохранения деталей песни: %w", err)
+	}
+
+	service.log.InfoContext(ctx, "детали песни получены", "song_id", id)
+	return song, nil
+}
+
 // GetSongByID получает песню по ID.
-func (service *SongService) GetSongByID(id int) (*domain.Song, error) {
+func (service *SongService) GetSongByID(ctx context.Context, id int) (_ *domain.Song, err error) {
+	ctx, span := tracer.Start(ctx, "SongService.GetSongByID")
+	defer func() { tracing.End(span, err) }()
+
 	if id <= 0 {
-		err := fmt.Errorf("некорректный ID песни: %d", id)
-		service.log.Printf("ошибка в GetSongByID: %v", err)
+		err := invalidSongID(id)
+		service.log.WarnContext(ctx, "ошибка в GetSongByID", "error", err)
 		return nil, err
 	}
 
-	song, err := service.repo.GetSongByID(id)
+	song, err := service.repo.GetSongByID(ctx, id)
 	if err != nil {
-		service.log.Printf("ошибка получения песни: id=%d, error=%v", id, err)
+		service.log.ErrorContext(ctx, "ошибка получения песни", "song_id", id, "error", err)
 		return nil, fmt.Errorf("ошибка получения песни: %w", err)
 	}
 
-	service.log.Printf("песня успешно получена: id=%d", id)
+	service.log.InfoContext(ctx, "песня успешно получена", "song_id", id)
 	return song, nil
 }
 
+// CountSongs возвращает общее количество песен и количество песен без деталей из внешнего API.
+func (service *SongService) CountSongs(ctx context.Context) (domain.SongCounts, error) {
+	counts, err := service.repo.CountSongs(ctx)
+	if err != nil {
+		service.log.ErrorContext(ctx, "ошибка подсчета песен", "error", err)
+		return domain.SongCounts{}, fmt.Errorf("ошибка подсчета песен: %w", err)
+	}
+	return counts, nil
+}
+
+// CacheStats возвращает счетчики кэша деталей песен.
+func (service *SongService) CacheStats() CacheStats {
+	if service.detailsCache == nil {
+		return CacheStats{}
+	}
+	return service.detailsCache.Stats()
+}
+
+// lookupSongDetails возвращает детали песни из кэша или из внешнего API.
+func (service *SongService) lookupSongDetails(ctx context.Context, group, song string) (*domain.SongDetail, error) {
+	if service.detailsCache == nil {
+		return service.fetchSongDetails(ctx, group, song)
+	}
+	return service.detailsCache.Get(ctx, group, song, func(ctx context.Context) (*domain.SongDetail, error) {
+		return service.fetchSongDetails(ctx, group, song)
+	})
+}
+
 // fetchSongDetails делает запрос к внешнему API и возвращает детали песни.
-func (service *SongService) fetchSongDetails(group, song string) (*domain.SongDetail, error) {
-	url := fmt.Sprintf("%s/info?group=%s&song=%s", service.apiBaseURL, group, song)
+// Контекст трассировки передается во внешний API в заголовке traceparent.
+func (service *SongService) fetchSongDetails(ctx context.Context, group, song string) (_ *domain.SongDetail, err error) {
+	ctx, span := tracer.Start(ctx, "SongService.fetchSongDetails", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+
+	query := url.Values{}
+	query.Set("group", group)
+	query.Set("song", song)
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, service.apiBaseURL+"/info?"+query.Encode(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка выполнения запроса к API: %w", err)
+		return nil, fmt.Errorf("ошибка формирования запроса к API: %w", err)
+	}
+	span.SetAttributes(semconv.HTTPRequestMethodKey.String(req.Method), semconv.URLFull(req.URL.String()), semconv.ServerAddress(req.URL.Hostname()))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := service.client.Do(req)
+	if err != nil {
+		return nil, &domain.UpstreamError{Err: fmt.Errorf("ошибка выполнения запроса к API: %w", err)}
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrSongDetailsNotFound
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ошибка API: получен статус %d", resp.StatusCode)
+		return nil, &domain.UpstreamError{StatusCode: resp.StatusCode, Err: fmt.Errorf("получен статус %d", resp.StatusCode)}
 	}
 
 	var details domain.SongDetail
 	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
-		return nil, fmt.Errorf("ошибка декодирования ответа API: %w", err)
+		return nil, &domain.UpstreamError{StatusCode: resp.StatusCode, Err: fmt.Errorf("ошибка декодирования ответа API: %w", err)}
 	}
 
 	return &details, nil
 }
-func (service *SongService) GetSongDetails(group, song string) (*domain.SongDetail, error) {
-	if group == "" || song == "" {
-		return nil, fmt.Errorf("параметры 'group' и 'song' обязательны")
+
+// PingUpstream проверяет, что внешний API отвечает. Любой ответ, кроме 5xx,
+// считается признаком работоспособности. Проверка идет мимо клиента сервиса,
+// чтобы не искажать метрики запросов деталей песен.
+func (service *SongService) PingUpstream(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, service.apiBaseURL+"/info", nil)
+	if err != nil {
+		return fmt.Errorf("ошибка формирования запроса к API: %w", err)
 	}
 
-	// Используем fetchSongDetails для запроса к внешнему API
-	details, err := service.fetchSongDetails(group, song)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return &domain.UpstreamError{Err: fmt.Errorf("ошибка выполнения запроса к API: %w", err)}
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return &domain.UpstreamError{StatusCode: resp.StatusCode, Err: fmt.Errorf("получен статус %d", resp.StatusCode)}
+	}
+	return nil
+}
+
+// GetSongDetails получает детали песни из кэша или из внешнего API.
+func (service *SongService) GetSongDetails(ctx context.Context, group, song string) (_ *domain.SongDetail, err error) {
+	ctx, span := tracer.Start(ctx, "SongService.GetSongDetails")
+	defer func() { tracing.End(span, err) }()
+
+	request := domain.SongCreateRequest{Group: group, Song: song}
+	if err := validation.Validate(&request); err != nil {
+		return nil, err
+	}
+	group, song = request.Group, request.Song
+
+	details, err := service.lookupSongDetails(ctx, group, song)
 
 	if err != nil {
-		service.log.Printf("ошибка получения данных о песне из API: group=%s, song=%s, error=%v", group, song, err)
+		service.log.ErrorContext(ctx, "ошибка получения данных о песне из API", "group", group, "song", song, "error", err)
 		return nil, err
 	}
 
-	service.log.Printf("успешно получены данные о песне: group=%s, song=%s", group, song)
+	service.log.InfoContext(ctx, "успешно получены данные о песне", "group", group, "song", song)
 	return details, nil
 }
 
+// invalidSongID возвращает ошибку валидации для недопустимого ID песни.
+func invalidSongID(id int) error {
+	return domain.NewValidationError("id", "invalid_id", id)
+}
+
 // calculateOffset вычисляет смещение для пагинации.
 func calculateOffset(page, limit int) int {
 	return (page - 1) * limit
@@ -0,0 +1,168 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"song-library/controller"
+	"song-library/domain"
+	"song-library/logging"
+	"song-library/middleware"
+	"song-library/migrations"
+	"song-library/repository"
+	"song-library/service"
+)
+
+// TestTracing проверяет, что запрос к API образует одну трассировку: спаны
+// сервиса и SQL-запросов вложены в серверный спан, а контекст трассировки
+// передается во внешний API в заголовке traceparent.
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	traceparents := make(chan string, 10)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/info" {
+			http.NotFound(w, r)
+			return
+		}
+		traceparents <- r.Header.Get("traceparent")
+		json.NewEncoder(w).Encode(domain.SongDetail{ReleaseDate: "16.07.2006", Text: "Text", Link: "https://example.com/song"})
+	}))
+	defer upstream.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	songService := service.NewSongService(newSQLiteStore(t, logger), logger, upstream.URL, upstream.Client(), nil)
+	songController := controller.NewSongController(songService)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /song", songController.AddSongHandler)
+	handler := middleware.Chain(logging.Routes(mux), middleware.RequestID, middleware.Tracing)
+
+	for _, name := range []string{"Hysteria", "Uprising"} {
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"group":"Muse","song":"` + name + `"}`)
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/song", body))
+		if rec.Code >= 300 {
+			t.Fatalf("POST /song: статус %d, %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	spans := exporter.GetSpans()
+	byID := make(map[trace.SpanID]tracetest.SpanStub, len(spans))
+	var servers []tracetest.SpanStub
+	for _, span := range spans {
+		byID[span.SpanContext.SpanID()] = span
+		if span.SpanKind == trace.SpanKindServer {
+			servers = append(servers, span)
+		}
+	}
+	if len(servers) != 2 {
+		t.Fatalf("серверных спанов %d, ожидалось по одному на запрос", len(servers))
+	}
+	if servers[0].SpanContext.TraceID() == servers[1].SpanContext.TraceID() {
+		t.Error("запросы попали в одну трассировку")
+	}
+	for _, server := range servers {
+		if server.Name != "POST /song" {
+			t.Errorf("серверный спан называется %q, ожидалось по шаблону маршрута", server.Name)
+		}
+	}
+
+	// serverOf возвращает серверный спан, в который вложен span.
+	serverOf := func(span tracetest.SpanStub) (tracetest.SpanStub, bool) {
+		for span.Parent.IsValid() {
+			parent, ok := byID[span.Parent.SpanID()]
+			if !ok {
+				return tracetest.SpanStub{}, false
+			}
+			span = parent
+		}
+		return span, span.SpanKind == trace.SpanKindServer
+	}
+
+	var serviceSpans, sqlSpans int
+	for _, span := range spans {
+		switch {
+		case strings.HasPrefix(span.Name, "SongService."):
+			serviceSpans++
+		case span.Name == "INSERT" || span.Name == "SELECT":
+			sqlSpans++
+		default:
+			continue
+		}
+		if _, ok := serverOf(span); !ok {
+			t.Errorf("спан %s не вложен в серверный спан", span.Name)
+		}
+		if span.Name == "SongService.AddSong" && byID[span.Parent.SpanID()].SpanKind != trace.SpanKindServer {
+			t.Errorf("родитель SongService.AddSong — %q, ожидался серверный спан", byID[span.Parent.SpanID()].Name)
+		}
+	}
+	if serviceSpans == 0 || sqlSpans == 0 {
+		t.Errorf("спанов сервиса %d, SQL-запросов %d; ожидались и те, и другие", serviceSpans, sqlSpans)
+	}
+
+	close(traceparents)
+	var received int
+	for header := range traceparents {
+		received++
+		parent, ok := byID[spanIDFromTraceparent(t, header)]
+		if !ok || parent.Name != "SongService.fetchSongDetails" {
+			t.Errorf("traceparent %q указывает на спан %q, ожидался SongService.fetchSongDetails", header, parent.Name)
+			continue
+		}
+		if server, ok := serverOf(parent); !ok || server.SpanContext.TraceID() != parent.SpanContext.TraceID() {
+			t.Errorf("traceparent %q не относится к трассировке запроса", header)
+		}
+	}
+	if received != 2 {
+		t.Errorf("внешний API получил %d запросов, ожидалось 2", received)
+	}
+}
+
+// spanIDFromTraceparent возвращает ID родительского спана из заголовка
+// traceparent вида 00-<trace-id>-<span-id>-<flags>.
+func spanIDFromTraceparent(t *testing.T, header string) trace.SpanID {
+	t.Helper()
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 {
+		t.Fatalf("некорректный traceparent %q", header)
+	}
+	id, err := trace.SpanIDFromHex(parts[2])
+	if err != nil {
+		t.Fatalf("некорректный traceparent %q: %v", header, err)
+	}
+	return id
+}
+
+// newSQLiteStore создает хранилище песен в SQLite во временном каталоге теста.
+func newSQLiteStore(t *testing.T, logger *slog.Logger) repository.SongStore {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "songs.db")
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_time_format=sqlite")
+	if err != nil {
+		t.Fatalf("открытие SQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := migrations.RunMigrations(db, "sqlite://"+path); err != nil {
+		t.Fatalf("миграции SQLite: %v", err)
+	}
+	return repository.NewSQLiteSongRepository(db, logger)
+}
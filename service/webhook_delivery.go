@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"song-library/domain"
+	"song-library/tracing"
+)
+
+// Заголовки запроса к подписчику.
+const (
+	HeaderWebhookEvent     = "X-Webhook-Event"     // Событие, например song.created
+	HeaderWebhookDelivery  = "X-Webhook-Delivery"  // ID доставки; при повторных попытках не меняется
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp" // Время отправки, секунды Unix
+	HeaderWebhookSignature = "X-Webhook-Signature" // sha256=<HMAC-SHA256 в hex>
+)
+
+// deliveryBatchSize ограничивает количество доставок, отправляемых одновременно.
+const deliveryBatchSize = 50
+
+// WebhookSignature возвращает значение заголовка X-Webhook-Signature для тела
+// body, отправленного в момент timestamp (секунды Unix): HMAC-SHA256 с ключом
+// secret от строки "<timestamp>.<body>". Получатель вычисляет подпись так же
+// и сравнивает ее с заголовком; время в подписи защищает от повтора старых запросов.
+func WebhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run доставляет события из очереди, пока не отменен ctx, и раз в час
+// удаляет из журнала завершенные доставки старше Retention. Доставка
+// выполняется не менее одного раза: получатель отличает повторы по
+// заголовку X-Webhook-Delivery. Начатые попытки при отмене ctx доводятся
+// до конца в пределах Timeout.
+func (service *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(service.config.PollInterval)
+	defer ticker.Stop()
+
+	var lastPrune time.Time
+	for {
+		if time.Since(lastPrune) >= time.Hour {
+			service.pruneDeliveries(ctx)
+			lastPrune = time.Now()
+		}
+
+		// Полная пачка означает, что в очереди могут быть еще доставки
+		for ctx.Err() == nil {
+			if service.deliverDue(ctx) < deliveryBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deliverDue отправляет пачку доставок, время которых наступило, и
+// возвращает ее размер.
+func (service *WebhookService) deliverDue(ctx context.Context) int {
+	// Пока идет попытка, доставку не возьмет другой экземпляр приложения
+	lease := service.config.Timeout + time.Minute
+	deliveries, err := service.store.ClaimDeliveries(ctx, time.Now().UTC(), lease, deliveryBatchSize)
+	if err != nil || len(deliveries) == 0 {
+		return 0
+	}
+
+	webhooks, err := service.store.GetWebhooks(ctx)
+	if err != nil {
+		return 0
+	}
+	byID := make(map[int]domain.Webhook, len(webhooks))
+	for _, webhook := range webhooks {
+		byID[webhook.ID] = webhook
+	}
+
+	// Попытки не прерываются остановкой приложения: их ограничивает Timeout
+	ctx = context.WithoutCancel(ctx)
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		webhook, ok := byID[delivery.WebhookID]
+		if !ok {
+			continue // Подписку удалили вместе с ее доставками
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempt := service.deliver(ctx, webhook, delivery)
+			if err := service.store.CompleteDelivery(ctx, attempt); err != nil {
+				// Доставка останется в очереди и после аренды будет отправлена снова
+				service.log.ErrorContext(ctx, "ошибка записи результата доставки", "webhook_id", webhook.ID, "delivery_id", delivery.ID, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+	return len(deliveries)
+}
+
+// deliver выполняет одну попытку доставки и возвращает ее результат со
+// временем следующей попытки по экспоненциальной паузе.
+func (service *WebhookService) deliver(ctx context.Context, webhook domain.Webhook, delivery domain.WebhookDelivery) domain.DeliveryAttempt {
+	attempt := domain.DeliveryAttempt{DeliveryID: delivery.ID, At: time.Now().UTC()}
+	status, err := service.send(ctx, webhook, delivery, attempt.At)
+	attempt.ResponseStatus = status
+
+	logger := service.log.With("webhook_id", webhook.ID, "delivery_id", delivery.ID, "event", delivery.Event, "attempt", delivery.Attempts+1)
+	if err == nil {
+		attempt.Delivered = true
+		logger.InfoContext(ctx, "событие доставлено", "status", status)
+		return attempt
+	}
+
+	attempt.Error = err.Error()
+	if delivery.Attempts+1 >= service.config.MaxAttempts {
+		logger.WarnContext(ctx, "доставка не удалась, попытки исчерпаны", "error", err)
+		return attempt
+	}
+	attempt.NextAttemptAt = attempt.At.Add(service.retryDelay(delivery.Attempts))
+	logger.WarnContext(ctx, "доставка не удалась, будет повтор", "retry_at", attempt.NextAttemptAt, "error", err)
+	return attempt
+}
+
+// retryDelay возвращает паузу после неудачной попытки, если до нее было
+// выполнено attempts попыток: RetryMin, затем вдвое больше, но не дольше RetryMax.
+func (service *WebhookService) retryDelay(attempts int) time.Duration {
+	delay := service.config.RetryMin
+	for i := 0; i < attempts && delay < service.config.RetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, service.config.RetryMax)
+}
+
+// send отправляет подписанное событие и возвращает HTTP-статус ответа
+// (0, если ответа не было). Успехом считается только ответ 2xx.
+func (service *WebhookService) send(ctx context.Context, webhook domain.Webhook, delivery domain.WebhookDelivery, at time.Time) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.send", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, service.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка формирования запроса: %w", err)
+	}
+	span.SetAttributes(semconv.HTTPRequestMethodKey.String(req.Method), semconv.URLFull(req.URL.String()), semconv.ServerAddress(req.URL.Hostname()))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	timestamp := at.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "song-library-webhooks")
+	req.Header.Set(HeaderWebhookEvent, delivery.Event)
+	req.Header.Set(HeaderWebhookDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderWebhookSignature, WebhookSignature(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := service.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer resp.Body.Close()
+	// Тело ответа не нужно, но дочитывается, чтобы соединение вернулось в пул
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("получен статус %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// pruneDeliveries удаляет из журнала завершенные доставки старше Retention.
+func (service *WebhookService) pruneDeliveries(ctx context.Context) {
+	pruned, err := service.store.PruneDeliveries(ctx, time.Now().Add(-service.config.Retention))
+	if err != nil {
+		service.log.ErrorContext(ctx, "ошибка очистки журнала доставок", "error", err)
+		return
+	}
+	if pruned > 0 {
+		service.log.InfoContext(ctx, "журнал доставок очищен", "pruned", pruned, "retention", service.config.Retention)
+	}
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"song-library/domain"
+	"song-library/repository"
+)
+
+// webhookReceiver — получатель вебхуков, который отвечает статусом status
+// и запоминает последний запрос.
+type webhookReceiver struct {
+	server *httptest.Server
+	status atomic.Int32
+	hits   atomic.Int32
+
+	mu     sync.Mutex
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T, status int) *webhookReceiver {
+	receiver := &webhookReceiver{}
+	receiver.status.Store(int32(status))
+	receiver.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			t.Error("перенаправление выполнено")
+			return
+		}
+		receiver.hits.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.header, receiver.body = r.Header.Clone(), body
+		receiver.mu.Unlock()
+		w.Header().Set("Location", "/moved")
+		w.WriteHeader(int(receiver.status.Load()))
+	}))
+	t.Cleanup(receiver.server.Close)
+	return receiver
+}
+
+// last возвращает заголовки и тело последнего запроса.
+func (receiver *webhookReceiver) last() (http.Header, []byte) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	return receiver.header, receiver.body
+}
+
+// newWebhookTest создает сервис вебхуков на хранилище в памяти с подпиской
+// на song.created по адресу receiver и одной доставкой в очереди.
+func newWebhookTest(t *testing.T, receiver *webhookReceiver, config WebhookConfig) (*WebhookService, domain.Webhook, int64) {
+	t.Helper()
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := repository.NewMemorySongStore(logger)
+	webhook, err := store.CreateWebhook(ctx, domain.Webhook{
+		URL:    receiver.server.URL + "/hooks",
+		Events: []string{domain.EventSongCreated},
+		Secret: "test-secret",
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if _, err := store.AddSong(ctx, domain.Song{Group: "Muse", Song: "Hysteria"}); err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+	deliveries, err := store.GetDeliveries(ctx, webhook.ID, "", 0, 10)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("GetDeliveries = %v, %v; ожидалась одна доставка", deliveries, err)
+	}
+	return NewWebhookService(store, logger, nil, config), webhook, deliveries[0].ID
+}
+
+// lastDelivery возвращает текущее состояние доставки id.
+func lastDelivery(t *testing.T, service *WebhookService, webhookID int, id int64) domain.WebhookDelivery {
+	t.Helper()
+	deliveries, err := service.GetDeliveries(context.Background(), webhookID, "", 0, 10)
+	if err != nil {
+		t.Fatalf("GetDeliveries: %v", err)
+	}
+	for _, delivery := range deliveries {
+		if delivery.ID == id {
+			return delivery
+		}
+	}
+	t.Fatalf("доставка %d не найдена", id)
+	return domain.WebhookDelivery{}
+}
+
+var testWebhookConfig = WebhookConfig{
+	Timeout:     time.Second,
+	MaxAttempts: 5,
+	RetryMin:    time.Second,
+	RetryMax:    3 * time.Second,
+}
+
+func TestWebhookDeliverySigned(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusNoContent)
+	service, webhook, id := newWebhookTest(t, receiver, testWebhookConfig)
+
+	if n := service.deliverDue(context.Background()); n != 1 {
+		t.Fatalf("deliverDue = %d, ожидалась одна доставка", n)
+	}
+
+	header, body := receiver.last()
+	timestamp, err := strconv.ParseInt(header.Get(HeaderWebhookTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("X-Webhook-Timestamp: %v", err)
+	}
+	if got, want := header.Get(HeaderWebhookSignature), WebhookSignature("test-secret", timestamp, body); got != want {
+		t.Errorf("X-Webhook-Signature = %q, ожидалось %q", got, want)
+	}
+	if got := header.Get(HeaderWebhookDelivery); got != strconv.FormatInt(id, 10) {
+		t.Errorf("X-Webhook-Delivery = %q, ожидалось %d", got, id)
+	}
+	if got := header.Get(HeaderWebhookEvent); got != domain.EventSongCreated {
+		t.Errorf("X-Webhook-Event = %q", got)
+	}
+
+	delivery := lastDelivery(t, service, webhook.ID, id)
+	if delivery.Status != domain.DeliveryDelivered || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusNoContent {
+		t.Errorf("доставка = %+v, ожидалась delivered после одной попытки", delivery)
+	}
+}
+
+func TestWebhookDeliveryRetry(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusServiceUnavailable)
+	service, webhook, id := newWebhookTest(t, receiver, testWebhookConfig)
+
+	service.deliverDue(context.Background())
+
+	delivery := lastDelivery(t, service, webhook.ID, id)
+	if delivery.Status != domain.DeliveryPending || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusServiceUnavailable {
+		t.Fatalf("доставка = %+v, ожидалась pending после одной попытки", delivery)
+	}
+	if delivery.NextAttemptAt == nil || delivery.LastAttemptAt == nil {
+		t.Fatalf("доставка = %+v, ожидалось время следующей попытки", delivery)
+	}
+	if delay := delivery.NextAttemptAt.Sub(*delivery.LastAttemptAt); delay != testWebhookConfig.RetryMin {
+		t.Errorf("пауза перед повтором %v, ожидалось %v", delay, testWebhookConfig.RetryMin)
+	}
+	// До наступления времени повтора доставка не отправляется снова
+	if n := service.deliverDue(context.Background()); n != 0 || receiver.hits.Load() != 1 {
+		t.Errorf("доставка отправлена до времени повтора: deliverDue = %d, запросов %d", n, receiver.hits.Load())
+	}
+}
+
+func TestWebhookRetryBackoff(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+	service, webhook, _ := newWebhookTest(t, receiver, testWebhookConfig)
+
+	// RetryMin*2^n, но не больше RetryMax
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	for attempts, delay := range want {
+		delivery := domain.WebhookDelivery{ID: 1, WebhookID: webhook.ID, Event: domain.EventSongCreated, Attempts: attempts}
+		attempt := service.deliver(context.Background(), webhook, delivery)
+		if attempt.Delivered || attempt.ResponseStatus != http.StatusInternalServerError {
+			t.Fatalf("попытка %d = %+v, ожидалась неудача со статусом 500", attempts+1, attempt)
+		}
+		if got := attempt.NextAttemptAt.Sub(attempt.At); got != delay {
+			t.Errorf("пауза после попытки %d: %v, ожидалось %v", attempts+1, got, delay)
+		}
+	}
+}
+
+func TestWebhookMaxAttempts(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusBadGateway)
+	config := testWebhookConfig
+	config.MaxAttempts = 1
+	service, webhook, id := newWebhookTest(t, receiver, config)
+
+	service.deliverDue(context.Background())
+
+	delivery := lastDelivery(t, service, webhook.ID, id)
+	if delivery.Status != domain.DeliveryFailed || delivery.Attempts != 1 || delivery.NextAttemptAt != nil {
+		t.Fatalf("доставка = %+v, ожидалась failed без следующей попытки", delivery)
+	}
+	if delivery.LastError == "" {
+		t.Error("причина неудачи не записана")
+	}
+}
+
+func TestWebhookRedeliver(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusBadGateway)
+	config := testWebhookConfig
+	config.MaxAttempts = 1
+	service, webhook, id := newWebhookTest(t, receiver, config)
+	ctx := context.Background()
+
+	service.deliverDue(ctx)
+	if delivery := lastDelivery(t, service, webhook.ID, id); delivery.Status != domain.DeliveryFailed {
+		t.Fatalf("доставка = %+v, ожидалась failed", delivery)
+	}
+
+	redelivered, err := service.Redeliver(ctx, webhook.ID, id)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if redelivered.Status != domain.DeliveryPending || redelivered.Attempts != 0 {
+		t.Errorf("Redeliver = %+v, ожидалась pending без попыток", redelivered)
+	}
+
+	receiver.status.Store(http.StatusOK)
+	if n := service.deliverDue(ctx); n != 1 {
+		t.Fatalf("deliverDue = %d, ожидалась повторная доставка", n)
+	}
+	if delivery := lastDelivery(t, service, webhook.ID, id); delivery.Status != domain.DeliveryDelivered || delivery.Attempts != 1 {
+		t.Errorf("доставка = %+v, ожидалась delivered после одной новой попытки", delivery)
+	}
+}
+
+func TestWebhookRedirectNotFollowed(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusFound)
+	service, webhook, id := newWebhookTest(t, receiver, testWebhookConfig)
+
+	service.deliverDue(context.Background())
+
+	delivery := lastDelivery(t, service, webhook.ID, id)
+	if delivery.Status != domain.DeliveryPending || delivery.ResponseStatus != http.StatusFound {
+		t.Errorf("доставка = %+v, ожидалась неудачная попытка со статусом 302", delivery)
+	}
+	if hits := receiver.hits.Load(); hits != 1 {
+		t.Errorf("получатель принял %d запросов, ожидался один", hits)
+	}
+}
+
+func TestNewWebhookServiceKeepsClient(t *testing.T) {
+	client := &http.Client{Timeout: time.Second}
+	service := NewWebhookService(nil, slog.New(slog.NewTextHandler(io.Discard, nil)), client, testWebhookConfig)
+
+	if client.CheckRedirect != nil {
+		t.Error("NewWebhookService изменил переданный клиент")
+	}
+	if service.client == client || service.client.CheckRedirect == nil {
+		t.Error("сервис не настроил свою копию клиента")
+	}
+	if service.client.Timeout != client.Timeout {
+		t.Errorf("Timeout копии = %v, ожидалось %v", service.client.Timeout, client.Timeout)
+	}
+}
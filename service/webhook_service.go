@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"song-library/domain"
+	"song-library/repository"
+	"song-library/tracing"
+	"song-library/validation"
+)
+
+// maxDeliveriesPage ограничивает количество доставок в одном ответе GetDeliveries.
+const maxDeliveriesPage = 500
+
+// WebhookConfig задает параметры доставки событий подписчикам.
+type WebhookConfig struct {
+	Timeout      time.Duration // Предельное время одного запроса к подписчику
+	MaxAttempts  int           // Количество попыток, после которого доставка считается неудачной
+	RetryMin     time.Duration // Пауза перед первой повторной попыткой; далее удваивается
+	RetryMax     time.Duration // Наибольшая пауза между попытками
+	PollInterval time.Duration // Как часто проверять очередь доставок
+	Retention    time.Duration // Сколько хранить завершенные доставки в журнале
+}
+
+// WebhookService управляет подписками на события песен и доставляет события
+// из очереди, которую пополняет хранилище песен.
+type WebhookService struct {
+	store  repository.WebhookStore
+	log    *slog.Logger
+	client *http.Client
+	config WebhookConfig
+}
+
+// NewWebhookService создает новый WebhookService. client выполняет запросы к
+// подписчикам; nil означает клиент с ограничением времени config.Timeout.
+// Перенаправления не выполняются: ответ 3xx считается неудачной попыткой.
+// Переданный client не изменяется: сервис настраивает свою копию.
+func NewWebhookService(store repository.WebhookStore, logger *slog.Logger, client *http.Client, config WebhookConfig) *WebhookService {
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	client = &c
+	return &WebhookService{store: store, log: logger, client: client, config: config}
+}
+
+// CreateWebhook создает подписку. Если ключ подписи не задан, создается
+// случайный; он возвращается только в ответе на создание.
+func (service *WebhookService) CreateWebhook(ctx context.Context, request domain.WebhookCreateRequest) (_ *domain.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.CreateWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	validationErr := &domain.ValidationError{}
+	if err := validation.Validate(&request); err != nil {
+		validationErr = err.(*domain.ValidationError)
+	}
+	if len(request.Events) == 0 {
+		validationErr.Add("events", "required")
+	}
+	for _, event := range request.Events {
+		if !slices.Contains(domain.WebhookEvents, event) {
+			validationErr.Add("events", "unknown_value", event)
+		}
+	}
+	if err := validationErr.Err(); err != nil {
+		service.log.WarnContext(ctx, "ошибка в CreateWebhook", "error", err)
+		return nil, err
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("ошибка создания ключа подписи: %w", err)
+		}
+		secret = hex.EncodeToString(key)
+	}
+
+	webhook, err := service.store.CreateWebhook(ctx, domain.Webhook{
+		URL:    request.URL,
+		Events: slices.Compact(slices.Sorted(slices.Values(request.Events))),
+		Secret: secret,
+	})
+	if err != nil {
+		service.log.ErrorContext(ctx, "ошибка создания вебхука", "url", request.URL, "error", err)
+		return nil, fmt.Errorf("ошибка создания вебхука: %w", err)
+	}
+
+	service.log.InfoContext(ctx, "вебхук создан", "webhook_id", webhook.ID, "url", webhook.URL, "events", webhook.Events)
+	return &webhook, nil
+}
+
+// GetWebhooks возвращает все подписки без ключей подписи.
+func (service *WebhookService) GetWebhooks(ctx context.Context) (_ []domain.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetWebhooks")
+	defer func() { tracing.End(span, err) }()
+
+	webhooks, err := service.store.GetWebhooks(ctx)
+	if err != nil {
+		service.log.ErrorContext(ctx, "ошибка получения вебхуков", "error", err)
+		return nil, fmt.Errorf("ошибка получения вебхуков: %w", err)
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+// GetWebhook возвращает подписку без ключа подписи.
+func (service *WebhookService) GetWebhook(ctx context.Context, id int) (_ *domain.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	webhook, err := service.store.GetWebhook(ctx, id)
+	if err != nil {
+		service.log.WarnContext(ctx, "ошибка получения вебхука", "webhook_id", id, "error", err)
+		return nil, fmt.Errorf("ошибка получения вебхука: %w", err)
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// DeleteWebhook удаляет подписку вместе с очередью и журналом ее доставок.
+func (service *WebhookService) DeleteWebhook(ctx context.Context, id int) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeleteWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	if err := service.store.DeleteWebhook(ctx, id); err != nil {
+		service.log.WarnContext(ctx, "ошибка удаления вебхука", "webhook_id", id, "error", err)
+		return fmt.Errorf("ошибка удаления вебхука: %w", err)
+	}
+
+	service.log.InfoContext(ctx, "вебхук удален", "webhook_id", id)
+	return nil
+}
+
+// GetDeliveries возвращает журнал доставок подписки от новых к старым: до
+// limit доставок с ID меньше before (0 — с самой новой), в состоянии status
+// (пустая строка — в любом).
+func (service *WebhookService) GetDeliveries(ctx context.Context, webhookID int, status string, before int64, limit int) (_ []domain.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	switch status {
+	case "", domain.DeliveryPending, domain.DeliveryDelivered, domain.DeliveryFailed:
+	default:
+		err := domain.NewValidationError("status", "unknown_value", status)
+		service.log.WarnContext(ctx, "ошибка в GetDeliveries", "error", err)
+		return nil, err
+	}
+	if limit <= 0 {
+		err := domain.NewValidationError("limit", "positive", limit)
+		service.log.WarnContext(ctx, "ошибка в GetDeliveries", "error", err)
+		return nil, err
+	}
+
+	deliveries, err := service.store.GetDeliveries(ctx, webhookID, status, before, min(limit, maxDeliveriesPage))
+	if err != nil {
+		service.log.WarnContext(ctx, "ошибка получения журнала доставок", "webhook_id", webhookID, "error", err)
+		return nil, fmt.Errorf("ошибка получения журнала доставок: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Redeliver ставит доставку в очередь заново с новым циклом попыток, в том
+// числе уже доставленную или неудачную.
+func (service *WebhookService) Redeliver(ctx context.Context, webhookID int, deliveryID int64) (_ *domain.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Redeliver")
+	defer func() { tracing.End(span, err) }()
+
+	delivery, err := service.store.Redeliver(ctx, webhookID, deliveryID)
+	if err != nil {
+		service.log.WarnContext(ctx, "ошибка повторной доставки", "webhook_id", webhookID, "delivery_id", deliveryID, "error", err)
+		return nil, fmt.Errorf("ошибка повторной доставки: %w", err)
+	}
+
+	service.log.InfoContext(ctx, "доставка поставлена в очередь заново", "webhook_id", webhookID, "delivery_id", deliveryID)
+	return delivery, nil
+}
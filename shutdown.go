@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"song-library/config"
+	"song-library/controller"
+)
+
+// serve запускает сервер и ждет отмены ctx по SIGINT или SIGTERM, после чего
+// плавно останавливает приложение:
+//
+//  1. проверка готовности начинает отвечать 503;
+//  2. через SHUTDOWN_DELAY сервер перестает принимать соединения, а фоновые
+//     задачи получают сигнал остановки;
+//  3. текущие запросы и фоновые задачи дорабатывают одновременно, каждые в
+//     пределах своего SHUTDOWN_GRACE_PERIOD, затем контексты оставшихся
+//     запросов отменяются;
+//  4. закрывается пул соединений с базой данных.
+//
+// Повторный сигнал во время остановки завершает процесс сразу.
+func serve(ctx context.Context, server *http.Server, cfg *config.Config, health *controller.HealthController, app *application) error {
+	// Контексты запросов наследуются от requestsCtx, чтобы отменить
+	// запросы, не успевшие завершиться за отведенное время
+	requestsCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	server.BaseContext = func(net.Listener) context.Context { return requestsCtx }
+
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- server.ListenAndServe() }()
+
+	select {
+	case err := <-serverErr:
+		app.Close()
+		return err
+	case <-ctx.Done():
+	}
+	// Обработка сигналов по умолчанию: повторный сигнал завершает процесс
+	signal.Reset(os.Interrupt, syscall.SIGTERM)
+
+	delay, grace := cfg.ShutdownDelay, cfg.ShutdownGracePeriod
+	app.logger.Info("начата остановка сервера", "delay", delay, "grace_period", grace)
+
+	health.BeginShutdown()
+	time.Sleep(delay)
+
+	// Фоновые задачи останавливаются параллельно с запросами и со своим
+	// сроком, чтобы долгие запросы не отнимали у них время на завершение
+	workersDone := make(chan struct{})
+	go func() {
+		defer close(workersDone)
+		workersCtx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+		app.StopWorkers(workersCtx)
+	}()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		app.logger.Warn("запросы не завершились за отведенное время и будут прерваны", "error", err)
+		cancelRequests()
+		server.Close()
+	}
+	if err := <-serverErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		app.logger.Error("ошибка сервера", "error", err)
+	}
+
+	// Пул соединений закрывается, когда его уже не используют ни запросы,
+	// ни фоновые задачи
+	<-workersDone
+	app.Close()
+	app.logger.Info("сервер остановлен")
+	return nil
+}
@@ -0,0 +1,66 @@
+package songio
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Format обозначает формат файла с песнями.
+type Format string
+
+const (
+	FormatCSV    Format = "csv"    // Значения, разделенные запятыми, с заголовком
+	FormatNDJSON Format = "ndjson" // JSON-объект на каждой строке
+	FormatTSV    Format = "tsv"    // Значения, разделенные табуляцией, с заголовком
+)
+
+// columns перечисляет колонки CSV и TSV в порядке записи.
+var columns = []string{"id", "group", "song", "release_date", "text", "link"}
+
+// ParseFormat разбирает название формата. Допускаются синонимы jsonl и json-lines.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "csv":
+		return FormatCSV, nil
+	case "ndjson", "jsonl", "json-lines":
+		return FormatNDJSON, nil
+	case "tsv":
+		return FormatTSV, nil
+	default:
+		return "", fmt.Errorf("неподдерживаемый формат: %q", name)
+	}
+}
+
+// FormatFromContentType определяет формат по заголовку Content-Type.
+func FormatFromContentType(contentType string) (Format, bool) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case "text/csv":
+		return FormatCSV, true
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return FormatNDJSON, true
+	case "text/tab-separated-values":
+		return FormatTSV, true
+	default:
+		return "", false
+	}
+}
+
+// FormatFromPath определяет формат по расширению файла.
+func FormatFromPath(path string) (Format, bool) {
+	format, err := ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+	return format, err == nil
+}
+
+// ContentType возвращает MIME-тип формата.
+func (format Format) ContentType() string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatTSV:
+		return "text/tab-separated-values; charset=utf-8"
+	default:
+		return "application/x-ndjson"
+	}
+}
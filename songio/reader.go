@@ -0,0 +1,144 @@
+package songio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"song-library/domain"
+	"strings"
+)
+
+// Record представляет одну прочитанную строку файла.
+// Если строку не удалось разобрать, Err содержит причину, а Song может быть пустой.
+type Record struct {
+	Line int
+	Song domain.Song
+	Err  error
+}
+
+// Reader последовательно читает песни из потока.
+type Reader interface {
+	// Next возвращает очередную запись или io.EOF, когда поток закончился.
+	Next() (Record, error)
+}
+
+// NewReader создает Reader для указанного формата.
+func NewReader(r io.Reader, format Format) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r, ',')
+	case FormatTSV:
+		return newCSVReader(r, '\t')
+	case FormatNDJSON:
+		return newNDJSONReader(r), nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат: %q", format)
+	}
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	index  map[string]int
+}
+
+func newCSVReader(r io.Reader, comma rune) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("файл пуст: ожидается строка заголовка")
+		}
+		return nil, fmt.Errorf("ошибка чтения заголовка: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		index[name] = i
+	}
+	for _, required := range []string{"group", "song"} {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("в заголовке отсутствует колонка %q", required)
+		}
+	}
+
+	return &csvReader{reader: reader, index: index}, nil
+}
+
+func (r *csvReader) Next() (Record, error) {
+	fields, err := r.reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return Record{}, io.EOF
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Record{Line: parseErr.StartLine, Err: err}, nil
+		}
+		return Record{}, err
+	}
+	line, _ := r.reader.FieldPos(0)
+
+	value := func(column string) string {
+		i, ok := r.index[column]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+		return fields[i]
+	}
+
+	return Record{
+		Line: line,
+		Song: domain.Song{
+			Group:       value("group"),
+			Song:        value("song"),
+			ReleaseDate: value("release_date"),
+			Text:        value("text"),
+			Link:        value("link"),
+		},
+	}, nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// maxNDJSONLine ограничивает длину одной строки NDJSON (текст песни может быть длинным).
+const maxNDJSONLine = 4 << 20
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLine)
+	return &ndjsonReader{scanner: scanner}
+}
+
+func (r *ndjsonReader) Next() (Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var song domain.Song
+		if err := json.Unmarshal(data, &song); err != nil {
+			return Record{Line: r.line, Err: fmt.Errorf("некорректный JSON: %w", err)}, nil
+		}
+		song.ID = 0
+		return Record{Line: r.line, Song: song}, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
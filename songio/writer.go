@@ -0,0 +1,88 @@
+package songio
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"song-library/domain"
+	"strconv"
+)
+
+// Writer последовательно записывает песни в поток.
+type Writer interface {
+	// Write записывает одну песню.
+	Write(song domain.Song) error
+	// Flush дописывает буферизованные данные в поток.
+	Flush() error
+}
+
+// NewWriter создает Writer для указанного формата.
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, ','), nil
+	case FormatTSV:
+		return newCSVWriter(w, '\t'), nil
+	case FormatNDJSON:
+		buffered := bufio.NewWriter(w)
+		return &ndjsonWriter{buffer: buffered, encoder: json.NewEncoder(buffered)}, nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат: %q", format)
+	}
+}
+
+type csvWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+	record        []string
+}
+
+func newCSVWriter(w io.Writer, comma rune) *csvWriter {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	return &csvWriter{writer: writer, record: make([]string, len(columns))}
+}
+
+func (w *csvWriter) Write(song domain.Song) error {
+	if !w.headerWritten {
+		if err := w.writer.Write(columns); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	w.record[0] = strconv.Itoa(song.ID)
+	w.record[1] = song.Group
+	w.record[2] = song.Song
+	w.record[3] = song.ReleaseDate
+	w.record[4] = song.Text
+	w.record[5] = song.Link
+	return w.writer.Write(w.record)
+}
+
+func (w *csvWriter) Flush() error {
+	if !w.headerWritten {
+		if err := w.writer.Write(columns); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type ndjsonWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(song domain.Song) error {
+	return w.encoder.Encode(song)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.buffer.Flush()
+}
@@ -0,0 +1,82 @@
+// Package tracing настраивает трассировку OpenTelemetry: экспорт спанов по
+// OTLP или в stdout и передачу контекста трассировки в заголовке traceparent
+// (W3C Trace Context).
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName — имя сервиса в спанах, если не задан OTEL_SERVICE_NAME.
+const ServiceName = "song-library"
+
+// Экспортеры спанов.
+const (
+	ExporterNone   = "none"   // Спаны не экспортируются, контекст трассировки только передается дальше
+	ExporterOTLP   = "otlp"   // OTLP по HTTP; адрес задают переменные OTEL_EXPORTER_OTLP_*
+	ExporterStdout = "stdout" // JSON в стандартный вывод
+)
+
+// Exporters перечисляет допустимые имена экспортеров.
+var Exporters = []string{ExporterOTLP, ExporterStdout, ExporterNone}
+
+// Setup устанавливает глобальные провайдер спанов и пропагатор W3C Trace
+// Context. exporter выбирает экспортер; пустая строка означает ExporterNone.
+// Возвращаемая функция дописывает накопленные спаны и останавливает экспорт.
+func Setup(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	spanExporter, err := newExporter(ctx, exporter)
+	if err != nil || spanExporter == nil {
+		return func(context.Context) error { return nil }, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка описания ресурса трассировки: %w", err)
+	}
+	// OTEL_SERVICE_NAME и OTEL_RESOURCE_ATTRIBUTES переопределяют значения по умолчанию
+	if fromEnv, err := resource.New(ctx, resource.WithFromEnv()); err == nil {
+		res, _ = resource.Merge(res, fromEnv)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spanExporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// newExporter создает экспортер по имени или возвращает nil для ExporterNone.
+func newExporter(ctx context.Context, exporter string) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(exporter) {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		return otlptracehttp.New(ctx)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("неизвестный экспортер спанов %q, допустимы %s", exporter, strings.Join(Exporters, ", "))
+	}
+}
+
+// End завершает span, отмечая его ошибкой, если err не nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,163 @@
+// Package validation проверяет структуры по декларативным правилам в тегах validate.
+//
+// Правила перечисляются через запятую и применяются к строковым полям по порядку:
+//
+//	trim       — обрезать пробелы по краям (изменяет поле)
+//	omitempty  — пропустить остальные правила для пустого значения
+//	required   — значение не может быть пустым
+//	max=N      — не длиннее N символов
+//	url        — абсолютная ссылка http или https
+//	date       — дата в формате ДД.ММ.ГГГГ или ГГГГ-ММ-ДД
+//
+// Имя поля в ошибках берется из тега json, код ошибки поля совпадает с именем правила.
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"song-library/domain"
+)
+
+// DateLayouts перечисляет допустимые форматы дат.
+var DateLayouts = []string{"02.01.2006", "2006-01-02"}
+
+// rule описывает одно правило проверки поля.
+type rule struct {
+	name  string
+	limit int
+}
+
+// fieldRules — правила одного поля структуры.
+type fieldRules struct {
+	index int
+	name  string
+	rules []rule
+}
+
+// rulesCache хранит разобранные правила по типу структуры.
+var rulesCache sync.Map // reflect.Type -> []fieldRules
+
+// Validate проверяет структуру, на которую указывает v, и возвращает
+// *domain.ValidationError со всеми ошибками полей или nil. Поля с правилом
+// trim обрезаются до проверки.
+func Validate(v any) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: ожидается указатель на структуру, получено %T", v))
+	}
+	value = value.Elem()
+
+	result := &domain.ValidationError{}
+	for _, field := range structRules(value.Type()) {
+		checkField(value.Field(field.index), field, result)
+	}
+	return result.Err()
+}
+
+// checkField применяет правила к строковому полю и записывает ошибки в result.
+func checkField(value reflect.Value, field fieldRules, result *domain.ValidationError) {
+	text := value.String()
+	for _, rule := range field.rules {
+		switch rule.name {
+		case "trim":
+			text = strings.TrimSpace(text)
+			value.SetString(text)
+		case "omitempty":
+			if text == "" {
+				return
+			}
+		case "required":
+			if text == "" {
+				result.Add(field.name, "required")
+				return
+			}
+		case "max":
+			if utf8.RuneCountInString(text) > rule.limit {
+				result.Add(field.name, "max", rule.limit)
+			}
+		case "url":
+			if !isHTTPURL(text) {
+				result.Add(field.name, "url")
+			}
+		case "date":
+			if !isDate(text) {
+				result.Add(field.name, "date")
+			}
+		}
+	}
+}
+
+func isHTTPURL(text string) bool {
+	parsed, err := url.Parse(text)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+func isDate(text string) bool {
+	for _, layout := range DateLayouts {
+		if _, err := time.Parse(layout, text); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// structRules возвращает разобранные правила полей типа t.
+func structRules(t reflect.Type) []fieldRules {
+	if cached, ok := rulesCache.Load(t); ok {
+		return cached.([]fieldRules)
+	}
+
+	var fields []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("validate")
+		if !ok {
+			continue
+		}
+		if field.Type.Kind() != reflect.String {
+			panic(fmt.Sprintf("validation: правила поддерживаются только для строк, поле %s.%s", t.Name(), field.Name))
+		}
+		fields = append(fields, fieldRules{index: i, name: jsonName(field), rules: parseRules(t, field, tag)})
+	}
+
+	rulesCache.Store(t, fields)
+	return fields
+}
+
+// parseRules разбирает тег validate. Ошибка в теге — ошибка программиста,
+// поэтому она приводит к панике при первой проверке типа.
+func parseRules(t reflect.Type, field reflect.StructField, tag string) []rule {
+	var rules []rule
+	for _, part := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "trim", "omitempty", "required", "url", "date":
+			rules = append(rules, rule{name: name})
+		case "max":
+			limit, err := strconv.Atoi(arg)
+			if err != nil || limit <= 0 {
+				panic(fmt.Sprintf("validation: некорректное правило %q в поле %s.%s", part, t.Name(), field.Name))
+			}
+			rules = append(rules, rule{name: name, limit: limit})
+		default:
+			panic(fmt.Sprintf("validation: неизвестное правило %q в поле %s.%s", part, t.Name(), field.Name))
+		}
+	}
+	return rules
+}
+
+// jsonName возвращает имя поля в JSON.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}